@@ -2,31 +2,87 @@ package main
 
 import (
 	"embed"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"os"
 	"pa11y-go-wrapper/internal/analysis"
 	"pa11y-go-wrapper/internal/api"
 	"pa11y-go-wrapper/internal/discovery"
+	"pa11y-go-wrapper/internal/webhook"
+	"strconv"
+	"time"
 )
 
 //go:embed frontend
 var frontendAssets embed.FS
 
 func main() {
+	workers := flag.Int("workers", getEnvInt("WORKER_COUNT", 4), "number of concurrent analysis workers")
+	perHost := flag.Int("per-host", getEnvInt("WORKER_PER_HOST_LIMIT", 2), "maximum concurrent analyses per host (0 = unlimited)")
+	queueSize := flag.Int("queue-size", getEnvInt("QUEUE_SIZE", 100), "maximum number of queued analyses")
+	analyzeTimeout := flag.Int("analyze-timeout", getEnvInt("ANALYZE_TIMEOUT", 120), "maximum duration in seconds of a direct analysis")
+	crawlDepth := flag.Int("crawl-depth", getEnvInt("CRAWL_MAX_DEPTH", discovery.DefaultCrawlDepth), "maximum link depth when crawling sites without a sitemap")
+	crawlPages := flag.Int("crawl-pages", getEnvInt("CRAWL_MAX_PAGES", discovery.DefaultCrawlPages), "maximum number of pages fetched when crawling sites without a sitemap")
+	queueOverflow := flag.Bool("queue-overflow", os.Getenv("QUEUE_OVERFLOW") == "true", "keep accepting analyses beyond the queue size, holding them in the store")
+	flag.Parse()
+
 	// Initialize the analysis service
-	analysisService := analysis.NewService(100) // Queue size of 100
+	store, err := newAnalysisStore()
+	if err != nil {
+		log.Fatalf("failed to create analysis store: %v", err)
+	}
+	analysisService := analysis.NewServiceWithStore(store, *queueSize)
+	if *queueOverflow {
+		analysisService.EnableOverflow()
+	}
+	if key := os.Getenv("PROFILES_KEY"); key != "" {
+		if err := analysisService.EnableProfiles(key); err != nil {
+			log.Fatalf("failed to enable credential profiles: %v", err)
+		}
+		log.Printf("Credential profiles enabled")
+	}
 	discoveryService, err := discovery.NewService()
 	if err != nil {
 		log.Fatalf("failed to create discovery service: %v", err)
 	}
+	discoveryService.SetCrawlOptions(discovery.CrawlOptions{MaxDepth: *crawlDepth, MaxPages: *crawlPages})
+	if !discoveryService.UsesLLM() {
+		log.Printf("No LLM configured, discovery selects URLs heuristically")
+	}
+
+	// Deliver analysis events to webhooks
+	webhookService := webhook.NewService()
+	if records, ok := store.(analysis.RecordStore); ok {
+		var sealer *analysis.Sealer
+		if key := os.Getenv("PROFILES_KEY"); key != "" {
+			if sealer, err = analysis.NewSealer(key); err != nil {
+				log.Fatalf("failed to create webhook secret sealer: %v", err)
+			}
+		}
+		webhookService, err = webhook.NewServiceWithStore(records, sealer)
+		if err != nil {
+			log.Fatalf("failed to load webhooks: %v", err)
+		}
+	}
+	webhookService.Start(analysisService.Events())
 
-	// Start the background worker
-	worker := analysis.NewWorker(analysisService)
-	worker.Start()
+	// Start the background worker pool
+	workerPool := analysis.NewWorkerPool(analysisService, *workers, *perHost)
+	workerPool.Start()
+	analysisService.SetWorkerPool(workerPool)
+	log.Printf("Started %d workers (per-host limit %d)", *workers, *perHost)
+
+	// Re-enqueue jobs that were pending or interrupted by the last shutdown
+	if n := analysisService.RequeuePending(); n > 0 {
+		log.Printf("Re-enqueued %d pending analyses", n)
+	}
 
 	// Create and run the Gin server
-	handlers := api.NewHandlers(analysisService, discoveryService)
+	handlers := api.NewHandlers(analysisService, discoveryService, workerPool)
+	handlers.SetAnalyzeTimeout(time.Duration(*analyzeTimeout) * time.Second)
+	handlers.SetWebhooks(webhookService)
 	router := api.NewRouter(handlers, frontendAssets)
 
 	addr := getServerAddr()
@@ -54,3 +110,35 @@ func getServerAddr() string {
 	}
 	return addr
 }
+
+// newAnalysisStore selects the analysis store from ANALYSIS_STORE ("memory" or "bolt").
+// The bolt database path can be set with ANALYSIS_STORE_PATH.
+func newAnalysisStore() (analysis.Store, error) {
+	switch kind := os.Getenv("ANALYSIS_STORE"); kind {
+	case "", "memory":
+		return analysis.NewMemoryStore(), nil
+	case "bolt":
+		path := os.Getenv("ANALYSIS_STORE_PATH")
+		if path == "" {
+			path = "data/analyses.db"
+		}
+		log.Printf("Using bolt analysis store at %s", path)
+		return analysis.NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown ANALYSIS_STORE %q", kind)
+	}
+}
+
+// getEnvInt reads an integer from the environment, falling back to def when unset or invalid.
+func getEnvInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,249 @@
+package analysis
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces secrets in API responses and reports.
+const redactedValue = "[REDACTED]"
+
+// Auth holds the credentials needed to scan a page behind a login. It is applied both to the
+// reachability check and to the pa11y run.
+type Auth struct {
+	// Headers are extra HTTP headers sent with every request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Cookies are sent with every request.
+	Cookies []Cookie `json:"cookies,omitempty"`
+	// BasicAuth sends HTTP basic authentication credentials.
+	BasicAuth *BasicAuth `json:"basicAuth,omitempty"`
+	// Actions is a pa11y actions script run before the page is tested,
+	// e.g. "set field #user to alice" or "click element #login".
+	Actions []string `json:"actions,omitempty"`
+}
+
+// Cookie is a cookie sent to the analyzed site.
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BasicAuth holds HTTP basic authentication credentials.
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// actionPrefixes are the pa11y actions accepted in an actions script.
+var actionPrefixes = []string{
+	"click element ",
+	"set field ",
+	"clear field ",
+	"check field ",
+	"uncheck field ",
+	"screen capture ",
+	"wait for ",
+	"navigate to url ",
+}
+
+// setFieldPattern matches the value of a "set field" action, which typically holds a secret.
+var setFieldPattern = regexp.MustCompile(`^(set field .+? to ).+$`)
+
+// Validate checks that the credentials can be sent to the site and handed to pa11y.
+func (a *Auth) Validate() error {
+	if a == nil {
+		return nil
+	}
+	for name, value := range a.Headers {
+		if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidOptions, name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: invalid value for header %q", ErrInvalidOptions, name)
+		}
+	}
+	for _, cookie := range a.Cookies {
+		if cookie.Name == "" || strings.ContainsAny(cookie.Name, "=; \t\r\n") || strings.ContainsAny(cookie.Value, "; \r\n") {
+			return fmt.Errorf("%w: invalid cookie %q", ErrInvalidOptions, cookie.Name)
+		}
+	}
+	if a.BasicAuth != nil && (a.BasicAuth.Username == "" || strings.Contains(a.BasicAuth.Username, ":")) {
+		return fmt.Errorf("%w: basic auth needs a username without colons", ErrInvalidOptions)
+	}
+	for _, action := range a.Actions {
+		if !validAction(action) {
+			return fmt.Errorf("%w: unsupported action %q", ErrInvalidOptions, action)
+		}
+	}
+	return nil
+}
+
+func validAction(action string) bool {
+	if strings.ContainsAny(action, "\r\n") {
+		return false
+	}
+	for _, prefix := range actionPrefixes {
+		if strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy of the credentials with every secret replaced, keeping header names,
+// cookie names, the basic auth username and the shape of the actions script.
+func (a *Auth) Redacted() *Auth {
+	if a == nil {
+		return nil
+	}
+
+	redacted := &Auth{}
+	if len(a.Headers) > 0 {
+		redacted.Headers = make(map[string]string, len(a.Headers))
+		for name := range a.Headers {
+			redacted.Headers[name] = redactedValue
+		}
+	}
+	for _, cookie := range a.Cookies {
+		redacted.Cookies = append(redacted.Cookies, Cookie{Name: cookie.Name, Value: redactedValue})
+	}
+	if a.BasicAuth != nil {
+		redacted.BasicAuth = &BasicAuth{Username: a.BasicAuth.Username, Password: redactedValue}
+	}
+	for _, action := range a.Actions {
+		redacted.Actions = append(redacted.Actions, setFieldPattern.ReplaceAllString(action, "${1}"+redactedValue))
+	}
+	return redacted
+}
+
+// isRedacted reports whether any secret of the credentials is the redaction placeholder, as
+// when a store could not keep them across a restart.
+func (a *Auth) isRedacted() bool {
+	if a == nil {
+		return false
+	}
+	for _, value := range a.Headers {
+		if value == redactedValue {
+			return true
+		}
+	}
+	for _, cookie := range a.Cookies {
+		if cookie.Value == redactedValue {
+			return true
+		}
+	}
+	if a.BasicAuth != nil && a.BasicAuth.Password == redactedValue {
+		return true
+	}
+	for _, action := range a.Actions {
+		if setFieldPattern.MatchString(action) && strings.HasSuffix(action, " to "+redactedValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply adds the headers, cookies and basic auth credentials to req.
+func (a *Auth) Apply(req *http.Request) {
+	if a == nil {
+		return
+	}
+	for name, value := range a.Headers {
+		req.Header.Set(name, value)
+	}
+	for _, cookie := range a.Cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if a.BasicAuth != nil {
+		req.SetBasicAuth(a.BasicAuth.Username, a.BasicAuth.Password)
+	}
+}
+
+// pa11yHeaders returns the HTTP headers pa11y must send, with cookies and basic auth folded in
+// since pa11y has no dedicated settings for them.
+func (a *Auth) pa11yHeaders() map[string]string {
+	headers := make(map[string]string, len(a.Headers)+2)
+	for name, value := range a.Headers {
+		headers[name] = value
+	}
+	if len(a.Cookies) > 0 {
+		pairs := make([]string, 0, len(a.Cookies))
+		for _, cookie := range a.Cookies {
+			pairs = append(pairs, cookie.Name+"="+cookie.Value)
+		}
+		headers["Cookie"] = strings.Join(pairs, "; ")
+	}
+	if a.BasicAuth != nil {
+		credentials := a.BasicAuth.Username + ":" + a.BasicAuth.Password
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	return headers
+}
+
+// merge returns the credentials of a, overridden and extended by those of override: headers and
+// basic auth of override win, cookies are combined and override's actions run after a's.
+func (a *Auth) merge(override *Auth) *Auth {
+	if a == nil {
+		return override
+	}
+	if override == nil {
+		return a
+	}
+
+	merged := &Auth{
+		Headers:   make(map[string]string, len(a.Headers)+len(override.Headers)),
+		BasicAuth: a.BasicAuth,
+	}
+	for name, value := range a.Headers {
+		merged.Headers[name] = value
+	}
+	for name, value := range override.Headers {
+		merged.Headers[name] = value
+	}
+	merged.Cookies = append(append(merged.Cookies, a.Cookies...), override.Cookies...)
+	if override.BasicAuth != nil {
+		merged.BasicAuth = override.BasicAuth
+	}
+	merged.Actions = append(append(merged.Actions, a.Actions...), override.Actions...)
+	return merged
+}
+
+// Summary describes the credentials without revealing any secret, e.g.
+// "headers: Authorization; cookies: session; basic auth: alice; 3 actions".
+func (a *Auth) Summary() string {
+	if a == nil {
+		return ""
+	}
+
+	var parts []string
+	if len(a.Headers) > 0 {
+		names := make([]string, 0, len(a.Headers))
+		for name := range a.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, "headers: "+strings.Join(names, ", "))
+	}
+	if len(a.Cookies) > 0 {
+		names := make([]string, 0, len(a.Cookies))
+		for _, cookie := range a.Cookies {
+			names = append(names, cookie.Name)
+		}
+		parts = append(parts, "cookies: "+strings.Join(names, ", "))
+	}
+	if a.BasicAuth != nil {
+		parts = append(parts, "basic auth: "+a.BasicAuth.Username)
+	}
+	switch len(a.Actions) {
+	case 0:
+	case 1:
+		parts = append(parts, "1 action")
+	default:
+		parts = append(parts, fmt.Sprintf("%d actions", len(a.Actions)))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAuth() *Auth {
+	return &Auth{
+		Headers:   map[string]string{"X-Api-Key": "s3cr3t-key"},
+		Cookies:   []Cookie{{Name: "session", Value: "s3cr3t-session"}},
+		BasicAuth: &BasicAuth{Username: "alice", Password: "s3cr3t-password"},
+		Actions: []string{
+			"set field #user to alice",
+			"set field #password to s3cr3t-login",
+			"click element #login",
+			"wait for url to be https://example.com/home",
+		},
+	}
+}
+
+func TestAuthRedacted(t *testing.T) {
+	auth := testAuth()
+	require.NoError(t, auth.Validate())
+
+	data, err := json.Marshal(auth.Redacted())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.Contains(t, string(data), "X-Api-Key")
+	assert.Contains(t, string(data), "click element #login")
+	assert.Equal(t, "set field #password to "+redactedValue, auth.Redacted().Actions[1])
+
+	// The original keeps its secrets.
+	assert.Equal(t, "s3cr3t-key", auth.Headers["X-Api-Key"])
+	assert.Equal(t, "headers: X-Api-Key; cookies: session; basic auth: alice; 4 actions", auth.Summary())
+}
+
+func TestAuthApply(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	testAuth().Apply(req)
+
+	assert.Equal(t, "s3cr3t-key", req.Header.Get("X-Api-Key"))
+	cookie, err := req.Cookie("session")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-session", cookie.Value)
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "alice", username)
+	assert.Equal(t, "s3cr3t-password", password)
+
+	headers := testAuth().pa11yHeaders()
+	assert.Equal(t, "session=s3cr3t-session", headers["Cookie"])
+	assert.Equal(t, req.Header.Get("Authorization"), headers["Authorization"])
+}
+
+func TestAuthValidate(t *testing.T) {
+	invalid := []*Auth{
+		{Headers: map[string]string{"Bad Header": "x"}},
+		{Headers: map[string]string{"X-Test": "a\r\nInjected: b"}},
+		{Cookies: []Cookie{{Name: "", Value: "x"}}},
+		{BasicAuth: &BasicAuth{Password: "x"}},
+		{Actions: []string{"rm -rf /"}},
+	}
+	for _, auth := range invalid {
+		assert.ErrorIs(t, auth.Validate(), ErrInvalidOptions, "%+v", auth)
+	}
+}
+
+func TestServiceRedactsAuth(t *testing.T) {
+	service := NewService(10)
+	a, err := service.Create(Request{URL: "http://example.com", Auth: testAuth()})
+	require.NoError(t, err)
+	assert.Equal(t, redactedValue, a.Auth.BasicAuth.Password)
+
+	got, ok := service.GetByID(a.ID)
+	require.True(t, ok)
+	assert.Equal(t, redactedValue, got.Auth.Headers["X-Api-Key"])
+	assert.Equal(t, redactedValue, service.GetAll()[0].Auth.Cookies[0].Value)
+
+	// Workers still see the credentials, and re-runs keep them.
+	raw, ok := service.getWithSecrets(a.ID)
+	require.True(t, ok)
+	assert.Equal(t, "s3cr3t-password", raw.Auth.BasicAuth.Password)
+
+	service.UpdateResult(a.ID, StatusCompleted, nil, "")
+	rerun, err := service.Rerun(a.ID)
+	require.NoError(t, err)
+	raw, _ = service.getWithSecrets(rerun.ID)
+	assert.Equal(t, "s3cr3t-key", raw.Auth.Headers["X-Api-Key"])
+}
+
+func TestCheckURLReachableWithAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, ok := r.BasicAuth(); !ok || password != "s3cr3t-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("welcome"))
+	}))
+	defer server.Close()
+
+	_, err := checkURLReachable(context.Background(), server.URL, nil)
+	assert.Error(t, err)
+
+	size, err := checkURLReachable(context.Background(), server.URL, testAuth())
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("welcome")), size)
+}
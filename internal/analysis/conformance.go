@@ -0,0 +1,224 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Conformance is the conformance level of a success criterion in an Accessibility Conformance
+// Report, using the terms of the VPAT template.
+type Conformance string
+
+const (
+	// ConformanceSupports means automated tests found no error for the criterion.
+	ConformanceSupports Conformance = "Supports"
+	// ConformancePartiallySupports means some, but not all, pages have errors for the criterion.
+	ConformancePartiallySupports Conformance = "Partially Supports"
+	// ConformanceDoesNotSupport means every page has errors for the criterion.
+	ConformanceDoesNotSupport Conformance = "Does Not Support"
+	// ConformanceNotTested means the runners that test the criterion did not run on every page,
+	// so the pages without errors were not checked for it.
+	ConformanceNotTested Conformance = "Not Tested"
+	// ConformanceNotEvaluated means no automated test covers the criterion, which must be
+	// reviewed manually.
+	ConformanceNotEvaluated Conformance = "Not Evaluated"
+)
+
+// ConformanceVersions lists the WCAG versions a conformance report can be made against.
+var ConformanceVersions = []string{"2.1", "2.2"}
+
+// DefaultConformanceVersion is the WCAG version of a conformance report when none is given.
+const DefaultConformanceVersion = "2.2"
+
+// runnerCriteria maps each runner known to this service to the success criteria its automated
+// rules test. Other criteria are not evaluated unless an issue is reported for them.
+var runnerCriteria = func() map[string]map[string]bool {
+	htmlcs := make(map[string]bool)
+	for _, criterion := range htmlcsCriteria {
+		htmlcs[criterion] = true
+	}
+	axe := make(map[string]bool)
+	for _, criterion := range axeCriteria {
+		axe[criterion] = true
+	}
+	return map[string]map[string]bool{"htmlcs": htmlcs, "axe": axe}
+}()
+
+// criterionRunners returns the known runners that test a success criterion, sorted by name.
+func criterionRunners(number string) []string {
+	var runners []string
+	for runner, criteria := range runnerCriteria {
+		if criteria[number] {
+			runners = append(runners, runner)
+		}
+	}
+	slices.Sort(runners)
+	return runners
+}
+
+// testsCriterion reports whether one of the runners of an analysis tests a success criterion.
+func testsCriterion(a *Analysis, number string) bool {
+	for _, runner := range a.RunnerList() {
+		if runnerCriteria[runner][number] {
+			return true
+		}
+	}
+	return false
+}
+
+// ConformanceReport is an Accessibility Conformance Report: the conformance of a set of pages
+// to each level A and AA success criterion of a WCAG version, derived from their issues.
+type ConformanceReport struct {
+	Version     string                 `json:"version"`
+	Pages       []ConformancePage      `json:"pages"`
+	Criteria    []ConformanceCriterion `json:"criteria"`
+	Summary     map[Conformance]int    `json:"summary"`
+	GeneratedAt time.Time              `json:"generatedAt"`
+}
+
+// ConformancePage is a page evaluated by a conformance report.
+type ConformancePage struct {
+	AnalysisID string    `json:"analysisId"`
+	URL        string    `json:"url"`
+	AnalyzedAt time.Time `json:"analyzedAt"`
+}
+
+// ConformanceCriterion is the conformance of the evaluated pages to one success criterion.
+type ConformanceCriterion struct {
+	Criterion   Criterion   `json:"criterion"`
+	Conformance Conformance `json:"conformance"`
+	Counts      IssueCounts `json:"counts"`
+	// FailingPages is the number of pages with errors for the criterion.
+	FailingPages int `json:"failingPages"`
+	// TestedPages is the number of pages analyzed with a runner that tests the criterion.
+	TestedPages int    `json:"testedPages"`
+	Remarks     string `json:"remarks"`
+}
+
+// ConformanceCriteria returns the level A and AA success criteria of a WCAG version, in
+// document order.
+func ConformanceCriteria(version string) ([]Criterion, error) {
+	if !slices.Contains(ConformanceVersions, version) {
+		return nil, fmt.Errorf("%w: WCAG version must be one of %s", ErrInvalidOptions, strings.Join(ConformanceVersions, ", "))
+	}
+	var criteria []Criterion
+	for _, c := range Criteria {
+		if c.Level == "AAA" || c.Version > version || (c.Removed != "" && c.Removed <= version) {
+			continue
+		}
+		criteria = append(criteria, c)
+	}
+	return criteria, nil
+}
+
+// NewConformanceReport builds the conformance report of completed analyses against a WCAG
+// version. A criterion with errors on every page does not support it, one with errors on some
+// pages partially supports it, and one without errors supports it if the runners of every page
+// test it. It is not tested if only some pages, or none, ran a runner that tests it, and not
+// evaluated if no known runner does. Warnings and notices are left to manual review.
+func NewConformanceReport(analyses []*Analysis, version string) (*ConformanceReport, error) {
+	criteria, err := ConformanceCriteria(version)
+	if err != nil {
+		return nil, err
+	}
+	if len(analyses) == 0 {
+		return nil, fmt.Errorf("%w: at least one completed analysis is required", ErrInvalidOptions)
+	}
+
+	report := &ConformanceReport{
+		Version:     version,
+		Pages:       make([]ConformancePage, 0, len(analyses)),
+		Criteria:    make([]ConformanceCriterion, 0, len(criteria)),
+		Summary:     make(map[Conformance]int),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	issues := make(map[string][]Issue)
+	failingPages := make(map[string]int)
+	for _, a := range analyses {
+		if a.Status != StatusCompleted {
+			return nil, fmt.Errorf("%w: analysis %s is %s", ErrInvalidState, a.ID, a.Status)
+		}
+		report.Pages = append(report.Pages, ConformancePage{AnalysisID: a.ID, URL: a.URL, AnalyzedAt: a.UpdatedAt})
+
+		failing := make(map[string]bool)
+		for _, issue := range a.Result {
+			number := WCAGCriterion(issue)
+			issues[number] = append(issues[number], issue)
+			if issue.Type == "error" {
+				failing[number] = true
+			}
+		}
+		for number := range failing {
+			failingPages[number]++
+		}
+	}
+
+	for _, c := range criteria {
+		cc := ConformanceCriterion{
+			Criterion:    c,
+			Counts:       CountIssues(issues[c.Number]),
+			FailingPages: failingPages[c.Number],
+		}
+		for _, a := range analyses {
+			if testsCriterion(a, c.Number) {
+				cc.TestedPages++
+			}
+		}
+		switch {
+		case cc.FailingPages == len(analyses):
+			cc.Conformance = ConformanceDoesNotSupport
+		case cc.FailingPages > 0:
+			cc.Conformance = ConformancePartiallySupports
+		case cc.TestedPages == len(analyses):
+			cc.Conformance = ConformanceSupports
+		case len(criterionRunners(c.Number)) > 0:
+			cc.Conformance = ConformanceNotTested
+		default:
+			cc.Conformance = ConformanceNotEvaluated
+		}
+		cc.Remarks = conformanceRemarks(cc, len(analyses))
+		report.Criteria = append(report.Criteria, cc)
+		report.Summary[cc.Conformance]++
+	}
+
+	return report, nil
+}
+
+// conformanceRemarks explains the conformance of a criterion, e.g.
+// "4 errors on 2 of 3 pages; 1 warning and 2 notices to review manually".
+func conformanceRemarks(cc ConformanceCriterion, pages int) string {
+	var remarks []string
+	switch cc.Conformance {
+	case ConformanceSupports:
+		remarks = append(remarks, "No errors found by automated tests")
+	case ConformanceNotTested:
+		remarks = append(remarks, fmt.Sprintf("Not tested on %d of %s; run %s to test it",
+			pages-cc.TestedPages, plural(pages, "page"), strings.Join(criterionRunners(cc.Criterion.Number), " or ")))
+	case ConformanceNotEvaluated:
+		remarks = append(remarks, "Not covered by automated tests")
+	default:
+		remarks = append(remarks, fmt.Sprintf("%s on %d of %s", plural(cc.Counts.Errors, "error"), cc.FailingPages, plural(pages, "page")))
+	}
+	var review []string
+	if cc.Counts.Warnings > 0 {
+		review = append(review, plural(cc.Counts.Warnings, "warning"))
+	}
+	if cc.Counts.Notices > 0 {
+		review = append(review, plural(cc.Counts.Notices, "notice"))
+	}
+	if len(review) > 0 {
+		remarks = append(remarks, strings.Join(review, " and ")+" to review manually")
+	}
+	return strings.Join(remarks, "; ")
+}
+
+// plural formats a count with a noun, adding an "s" unless the count is one.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
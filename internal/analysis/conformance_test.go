@@ -0,0 +1,101 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConformanceCriteria(t *testing.T) {
+	wcag21, err := ConformanceCriteria("2.1")
+	assert.NoError(t, err)
+	assert.Len(t, wcag21, 50)
+
+	wcag22, err := ConformanceCriteria("2.2")
+	assert.NoError(t, err)
+	assert.Len(t, wcag22, 55)
+
+	numbers := func(criteria []Criterion) map[string]bool {
+		m := make(map[string]bool)
+		for _, c := range criteria {
+			assert.NotEqual(t, "AAA", c.Level)
+			m[c.Number] = true
+		}
+		return m
+	}
+	assert.True(t, numbers(wcag21)["4.1.1"], "parsing is still part of WCAG 2.1")
+	assert.False(t, numbers(wcag22)["4.1.1"], "parsing was removed in WCAG 2.2")
+	assert.False(t, numbers(wcag21)["2.5.8"])
+	assert.True(t, numbers(wcag22)["2.5.8"])
+
+	_, err = ConformanceCriteria("3.0")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+func TestNewConformanceReport(t *testing.T) {
+	contrast := Issue{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error", TypeCode: 1}
+	alt := Issue{Code: "image-alt", Type: "error", TypeCode: 1}
+	title := Issue{Code: "WCAG2AA.Principle2.Guideline2_4.2_4_2.H25.2", Type: "warning", TypeCode: 2}
+
+	home := &Analysis{ID: "home", URL: "https://example.com/", Status: StatusCompleted, Result: []Issue{contrast, contrast, alt, title}}
+	about := &Analysis{ID: "about", URL: "https://example.com/about", Status: StatusCompleted, Result: []Issue{contrast}}
+
+	report, err := NewConformanceReport([]*Analysis{home, about}, "2.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2", report.Version)
+	assert.Len(t, report.Pages, 2)
+	assert.Len(t, report.Criteria, 55)
+
+	byNumber := make(map[string]ConformanceCriterion)
+	for _, cc := range report.Criteria {
+		byNumber[cc.Criterion.Number] = cc
+	}
+	assert.Equal(t, ConformanceDoesNotSupport, byNumber["1.4.3"].Conformance)
+	assert.Equal(t, "3 errors on 2 of 2 pages", byNumber["1.4.3"].Remarks)
+	assert.Equal(t, ConformancePartiallySupports, byNumber["1.1.1"].Conformance)
+	assert.Equal(t, "1 error on 1 of 2 pages", byNumber["1.1.1"].Remarks)
+	assert.Equal(t, ConformanceSupports, byNumber["2.4.2"].Conformance)
+	assert.Equal(t, "No errors found by automated tests; 1 warning to review manually", byNumber["2.4.2"].Remarks)
+	assert.Equal(t, ConformanceSupports, byNumber["3.2.2"].Conformance, "only HTML_CodeSniffer tests 3.2.2")
+	assert.Equal(t, ConformanceNotTested, byNumber["1.4.12"].Conformance, "only axe tests 1.4.12")
+	assert.Equal(t, "Not tested on 2 of 2 pages; run axe to test it", byNumber["1.4.12"].Remarks)
+	assert.Equal(t, ConformanceNotEvaluated, byNumber["3.3.7"].Conformance)
+	assert.Equal(t, "Not covered by automated tests", byNumber["3.3.7"].Remarks)
+
+	total := 0
+	for _, n := range report.Summary {
+		total += n
+	}
+	assert.Equal(t, 55, total)
+	assert.Equal(t, 1, report.Summary[ConformanceDoesNotSupport])
+	assert.Equal(t, 1, report.Summary[ConformancePartiallySupports])
+}
+
+func TestNewConformanceReportErrors(t *testing.T) {
+	_, err := NewConformanceReport(nil, "2.2")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = NewConformanceReport([]*Analysis{{ID: "a", Status: StatusCompleted}}, "1.0")
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = NewConformanceReport([]*Analysis{{ID: "a", Status: StatusPending}}, "2.1")
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+func TestConformanceReportFollowsRunners(t *testing.T) {
+	both := &Analysis{ID: "both", URL: "https://example.com/", Status: StatusCompleted, Runners: []string{"axe", "htmlcs"}}
+	axe := &Analysis{ID: "axe", URL: "https://example.com/about", Status: StatusCompleted, Runner: "axe"}
+
+	report, err := NewConformanceReport([]*Analysis{both, axe}, "2.2")
+	assert.NoError(t, err)
+	byNumber := make(map[string]ConformanceCriterion)
+	for _, cc := range report.Criteria {
+		byNumber[cc.Criterion.Number] = cc
+	}
+	assert.Equal(t, ConformanceSupports, byNumber["1.4.3"].Conformance, "both runners test contrast")
+	assert.Equal(t, 2, byNumber["1.4.3"].TestedPages)
+	assert.Equal(t, ConformanceSupports, byNumber["2.5.8"].Conformance)
+	assert.Equal(t, ConformanceNotTested, byNumber["3.2.2"].Conformance, "HTML_CodeSniffer did not run on every page")
+	assert.Equal(t, 1, byNumber["3.2.2"].TestedPages)
+	assert.Equal(t, "Not tested on 1 of 2 pages; run htmlcs to test it", byNumber["3.2.2"].Remarks)
+}
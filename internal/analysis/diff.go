@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// IssueDiff lists the issues that appeared, disappeared or stayed between two analyses.
+type IssueDiff struct {
+	BaseID    string  `json:"baseId"`
+	HeadID    string  `json:"headId"`
+	BaseURL   string  `json:"baseUrl"`
+	HeadURL   string  `json:"headUrl"`
+	New       []Issue `json:"new"`
+	Resolved  []Issue `json:"resolved"`
+	Unchanged []Issue `json:"unchanged"`
+}
+
+// Fingerprint identifies an issue across runs of the same page. It is built from the
+// issue code, its selector and its context with whitespace normalized.
+func Fingerprint(issue Issue) string {
+	h := sha1.New()
+	h.Write([]byte(issue.Code))
+	h.Write([]byte{0})
+	h.Write([]byte(issue.Selector))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeContext(issue.Context)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeContext collapses whitespace, and drops it around tags, so that reformatted
+// markup keeps its fingerprint.
+func normalizeContext(context string) string {
+	normalized := strings.Join(strings.Fields(context), " ")
+	normalized = strings.ReplaceAll(normalized, "> ", ">")
+	return strings.ReplaceAll(normalized, " <", "<")
+}
+
+// Diff compares the issues of base and head. Issues sharing a fingerprint are matched one
+// to one, so a duplicated finding that appears once more in head is reported as new.
+func Diff(base, head *Analysis) *IssueDiff {
+	diff := &IssueDiff{
+		BaseID:    base.ID,
+		HeadID:    head.ID,
+		BaseURL:   base.URL,
+		HeadURL:   head.URL,
+		New:       []Issue{},
+		Resolved:  []Issue{},
+		Unchanged: []Issue{},
+	}
+
+	remaining := make(map[string]int, len(base.Result))
+	for _, issue := range base.Result {
+		remaining[Fingerprint(issue)]++
+	}
+
+	for _, issue := range head.Result {
+		fp := Fingerprint(issue)
+		if remaining[fp] > 0 {
+			remaining[fp]--
+			diff.Unchanged = append(diff.Unchanged, issue)
+		} else {
+			diff.New = append(diff.New, issue)
+		}
+	}
+
+	for _, issue := range base.Result {
+		fp := Fingerprint(issue)
+		if remaining[fp] > 0 {
+			remaining[fp]--
+			diff.Resolved = append(diff.Resolved, issue)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,33 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	contrast := Issue{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Selector: "#nav > a", Context: "<a href=\"/\">Home</a>"}
+	alt := Issue{Code: "WCAG2AA.Principle1.Guideline1_1.1_1_1.H37", Selector: "img", Context: "<img src=\"logo.png\">"}
+	label := Issue{Code: "WCAG2AA.Principle1.Guideline1_3.1_3_1.F68", Selector: "#search", Context: "<input id=\"search\">"}
+
+	reformatted := contrast
+	reformatted.Context = "<a href=\"/\">\n  Home</a>"
+
+	base := &Analysis{ID: "base", Result: []Issue{contrast, alt}}
+	head := &Analysis{ID: "head", Result: []Issue{reformatted, label}}
+
+	diff := Diff(base, head)
+	assert.Equal(t, []Issue{label}, diff.New)
+	assert.Equal(t, []Issue{alt}, diff.Resolved)
+	assert.Equal(t, []Issue{reformatted}, diff.Unchanged, "whitespace changes in the context keep the fingerprint")
+}
+
+func TestDiffDuplicates(t *testing.T) {
+	issue := Issue{Code: "color-contrast", Selector: ".btn", Context: "<button class=\"btn\">Go</button>"}
+
+	diff := Diff(&Analysis{Result: []Issue{issue}}, &Analysis{Result: []Issue{issue, issue}})
+	assert.Len(t, diff.Unchanged, 1)
+	assert.Len(t, diff.New, 1)
+	assert.Empty(t, diff.Resolved)
+}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTimeout is returned when a direct analysis does not finish before its deadline.
+	ErrTimeout = errors.New("analysis timed out")
+	// ErrAnalysisFailed is returned when a direct analysis could not analyze the page.
+	ErrAnalysisFailed = errors.New("analysis failed")
+	// ErrBusy is returned when a direct analysis cannot start because too many analyses are
+	// running, overall or against its host.
+	ErrBusy = errors.New("too many analyses are running")
+)
+
+// SetWorkerPool makes direct analyses share the limits of pool: no more direct analyses run at
+// the same time than it has workers, and they count toward its per-host limit. Without a pool,
+// direct analyses are not limited.
+func (s *Service) SetWorkerPool(pool *WorkerPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pool = pool
+}
+
+// Analyze runs an analysis right away, bypassing the queue, and returns it with its result. The run is bound to ctx, so it is aborted when ctx is cancelled or its
+// deadline expires. When record is true the analysis is stored like a queued one and can be
+// cancelled while it runs; otherwise it is only returned, without an ID.
+// Besides the validation errors of Create, it returns ErrTimeout if ctx's deadline expires
+// first and ErrAnalysisFailed if the page could not be analyzed; the returned analysis then
+// holds the failure. A recorded analysis cancelled or deleted before it starts returns
+// ErrInvalidState, and one that exceeds the limits of the worker pool (see SetWorkerPool)
+// returns ErrBusy without running.
+func (s *Service) Analyze(ctx context.Context, req Request, record bool) (*Analysis, error) {
+	analysis, err := s.newAnalysis(req)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	pool := s.pool
+	s.mu.Unlock()
+	if pool != nil {
+		release, err := pool.acquireDirect(hostOf(analysis.URL))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	analysis.Status = StatusPending
+	analysis.CreatedAt = time.Now()
+	analysis.UpdatedAt = analysis.CreatedAt
+
+	if !record {
+		applyStatus(analysis, StatusProcessing, time.Now())
+		result, runErr := s.execute(ctx, analysis, func(size int64) {
+			analysis.SizeBytes = size
+		})
+		status, message, err := directOutcome(ctx, runErr)
+		applyResult(analysis, status, result, message, time.Now())
+		return analysis.redacted(), err
+	}
+
+	analysis.ID = uuid.New().String()
+	if err := s.store.Create(analysis); err != nil {
+		return nil, err
+	}
+	s.events.Publish(newEvent(EventCreated, analysis))
+	runCtx, ok := s.startProcessing(ctx, analysis.ID)
+	if !ok {
+		// The analysis was cancelled or deleted before it started.
+		stored, _ := s.GetByID(analysis.ID)
+		return stored, ErrInvalidState
+	}
+	result, runErr := s.execute(runCtx, analysis, func(size int64) {
+		s.UpdateSize(analysis.ID, size)
+	})
+	status, message, err := directOutcome(ctx, runErr)
+	s.finishProcessing(analysis.ID, status, result, message)
+
+	stored, _ := s.GetByID(analysis.ID)
+	return stored, err
+}
+
+// directOutcome turns the error of a direct run into the analysis status, its error message
+// and the error returned to the caller.
+func directOutcome(ctx context.Context, runErr error) (AnalysisStatus, string, error) {
+	switch {
+	case runErr == nil:
+		return StatusCompleted, "", nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return StatusFailed, ErrTimeout.Error(), ErrTimeout
+	default:
+		return StatusFailed, runErr.Error(), fmt.Errorf("%w: %v", ErrAnalysisFailed, runErr)
+	}
+}
+
+// execute checks that the page is reachable and runs pa11y on it with the analysis' settings,
+// applying its credential profile. onReachable, if not nil, receives the size of the page once
+// the reachability check succeeds.
+func (s *Service) execute(ctx context.Context, analysis *Analysis, onReachable func(size int64)) ([]Issue, error) {
+	if analysis.Auth.isRedacted() {
+		return nil, errors.New("inline credentials were not kept across a restart; submit the analysis again")
+	}
+	auth, options, err := s.resolveProfile(analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := checkURLReachable(ctx, analysis.URL, auth)
+	if err != nil {
+		return nil, fmt.Errorf("URL not reachable: %v", err)
+	}
+	if onReachable != nil {
+		onReachable(size)
+	}
+
+	// RunPa11y defaults to htmlcs when no runner is specified
+	return RunPa11y(ctx, analysis.URL, analysis.RunnerList(), options, auth)
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened to an analysis.
+type EventType string
+
+const (
+	// EventCreated is published when an analysis is admitted.
+	EventCreated EventType = "created"
+	// EventStatus is published when the status of an analysis changes, including when it
+	// finishes with its result.
+	EventStatus EventType = "status"
+	// EventSize is published when the size of the analyzed page is known.
+	EventSize EventType = "size"
+	// EventDeleted is published when an analysis is deleted.
+	EventDeleted EventType = "deleted"
+)
+
+// Event describes a change to an analysis.
+type Event struct {
+	Type         EventType      `json:"type"`
+	ID           string         `json:"id"`
+	URL          string         `json:"url"`
+	Status       AnalysisStatus `json:"status"`
+	SizeBytes    int64          `json:"sizeBytes,omitempty"`
+	ErrorMessage string         `json:"errorMessage,omitempty"`
+	// Issues is set once an analysis is completed.
+	Issues *IssueCounts `json:"issues,omitempty"`
+	Time   time.Time    `json:"time"`
+}
+
+// Finished reports whether the event leaves the analysis in a final state.
+func (e Event) Finished() bool {
+	if e.Type == EventDeleted {
+		return true
+	}
+	return e.Type == EventStatus && (e.Status == StatusCompleted || e.Status == StatusFailed || e.Status == StatusCancelled)
+}
+
+// newEvent builds an event of type t from the current state of analysis.
+func newEvent(t EventType, analysis *Analysis) Event {
+	e := Event{
+		Type:         t,
+		ID:           analysis.ID,
+		URL:          analysis.URL,
+		Status:       analysis.Status,
+		SizeBytes:    analysis.SizeBytes,
+		ErrorMessage: analysis.ErrorMessage,
+		Time:         time.Now(),
+	}
+	if analysis.Status == StatusCompleted {
+		counts := CountIssues(analysis.Result)
+		e.Issues = &counts
+	}
+	return e
+}
+
+// subscriptionBuffer is the number of events a subscriber may fall behind before events
+// are dropped for it.
+const subscriptionBuffer = 256
+
+// Broker fans analysis events out to subscribers. Publishing never blocks: a subscriber
+// that does not keep up misses events, unless it subscribed with SubscribeUnbounded.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// NewBroker creates a broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events accepted by its filter on C until it is closed.
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	filter func(Event) bool
+	broker *Broker
+	// queue holds the events of an unbounded subscription until they are received; it is nil
+	// for buffered subscriptions.
+	queue *eventQueue
+}
+
+// Subscribe registers a subscriber for the events accepted by filter, or all events if
+// filter is nil. The subscription must be closed when no longer needed.
+func (b *Broker) Subscribe(filter func(Event) bool) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// SubscribeUnbounded is like Subscribe, but never drops events: those the subscriber has not
+// received yet are queued in memory. It is meant for subscribers that must see every event,
+// such as webhook dispatch, and keep up on average.
+func (b *Broker) SubscribeUnbounded(filter func(Event) bool) *Subscription {
+	ch := make(chan Event)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, broker: b, queue: newEventQueue()}
+	go sub.queue.pump(ch)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Close unregisters the subscription and closes its channel. Events still queued for an
+// unbounded subscription are discarded.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if _, ok := s.broker.subscribers[s]; ok {
+		delete(s.broker.subscribers, s)
+		if s.queue != nil {
+			close(s.queue.done)
+		} else {
+			close(s.ch)
+		}
+	}
+}
+
+// Publish delivers e to every interested subscriber.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		if sub.queue != nil {
+			sub.queue.push(e)
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// The subscriber is not keeping up; drop the event rather than stall the service.
+		}
+	}
+}
+
+// eventQueue is the unbounded queue of an unbounded subscription.
+type eventQueue struct {
+	mu     sync.Mutex
+	events []Event
+	// ready is signalled when events are pushed.
+	ready chan struct{}
+	// done is closed when the subscription is closed.
+	done chan struct{}
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{ready: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+// push appends an event without blocking.
+func (q *eventQueue) push(e Event) {
+	q.mu.Lock()
+	q.events = append(q.events, e)
+	q.mu.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pump sends the queued events to ch in order until the subscription is closed, then closes ch.
+func (q *eventQueue) pump(ch chan<- Event) {
+	defer close(ch)
+	for {
+		q.mu.Lock()
+		if len(q.events) == 0 {
+			q.mu.Unlock()
+			select {
+			case <-q.ready:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+		e := q.events[0]
+		q.events[0] = Event{}
+		q.events = q.events[1:]
+		q.mu.Unlock()
+
+		select {
+		case ch <- e:
+		case <-q.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServicePublishesEvents(t *testing.T) {
+	service := NewService(10)
+	all := service.Events().Subscribe(nil)
+	defer all.Close()
+
+	a, err := service.Create(Request{URL: "http://example.com"})
+	require.NoError(t, err)
+	other, err := service.Create(Request{URL: "http://example.org"})
+	require.NoError(t, err)
+
+	one := service.Events().Subscribe(func(e Event) bool { return e.ID == a.ID })
+	defer one.Close()
+
+	service.UpdateStatus(a.ID, StatusProcessing)
+	service.UpdateSize(a.ID, 42)
+	service.UpdateResult(a.ID, StatusCompleted, []Issue{{Type: "error"}}, "")
+	require.NoError(t, service.Delete(other.ID))
+
+	var types []EventType
+	for range 6 {
+		types = append(types, (<-all.C).Type)
+	}
+	assert.Equal(t, []EventType{EventCreated, EventCreated, EventStatus, EventSize, EventStatus, EventDeleted}, types)
+
+	assert.Equal(t, StatusProcessing, (<-one.C).Status)
+	assert.Equal(t, int64(42), (<-one.C).SizeBytes)
+	done := <-one.C
+	assert.True(t, done.Finished())
+	assert.Equal(t, 1, done.Issues.Errors)
+	assert.Empty(t, one.C, "events of other analyses are filtered out")
+}
+
+func TestBrokerDropsEventsForSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(nil)
+	for range subscriptionBuffer + 10 {
+		broker.Publish(Event{Type: EventStatus})
+	}
+	assert.Len(t, sub.C, subscriptionBuffer)
+
+	sub.Close()
+	sub.Close()
+	broker.Publish(Event{Type: EventStatus})
+}
+
+func TestBrokerUnboundedSubscriptionKeepsEveryEvent(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.SubscribeUnbounded(func(e Event) bool { return e.Type == EventStatus })
+	for i := range subscriptionBuffer * 4 {
+		broker.Publish(Event{Type: EventStatus, SizeBytes: int64(i)})
+		broker.Publish(Event{Type: EventSize})
+	}
+
+	for i := range subscriptionBuffer * 4 {
+		assert.Equal(t, int64(i), (<-sub.C).SizeBytes)
+	}
+
+	sub.Close()
+	sub.Close()
+	broker.Publish(Event{Type: EventStatus})
+	_, open := <-sub.C
+	assert.False(t, open, "the channel is closed with the subscription")
+}
@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GateStatus is the verdict of a quality gate.
+type GateStatus string
+
+const (
+	// GatePassed means the analysis completed within every limit of its quality gate.
+	GatePassed GateStatus = "passed"
+	// GateFailed means the analysis broke a limit of its quality gate or did not complete.
+	GateFailed GateStatus = "failed"
+)
+
+// QualityGate sets the limits an analysis must stay within to pass, so that CI pipelines can
+// check a single field. Unset limits are not checked.
+type QualityGate struct {
+	// MaxErrors is the number of error issues allowed.
+	MaxErrors *int `json:"maxErrors,omitempty"`
+	// MaxWarnings is the number of warning issues allowed.
+	MaxWarnings *int `json:"maxWarnings,omitempty"`
+	// ForbiddenCodes fail the gate when any issue has one of them as its code or as a
+	// dot-separated prefix of its code, e.g. "WCAG2AA.Principle1.Guideline1_4.1_4_3".
+	ForbiddenCodes []string `json:"forbiddenCodes,omitempty"`
+	// MinScore is the lowest Score allowed, from 0 to 100.
+	MinScore *int `json:"minScore,omitempty"`
+}
+
+// Validate checks that the limits of the gate are within range.
+func (g *QualityGate) Validate() error {
+	if g == nil {
+		return nil
+	}
+	if g.MaxErrors != nil && *g.MaxErrors < 0 {
+		return fmt.Errorf("%w: maxErrors must not be negative", ErrInvalidOptions)
+	}
+	if g.MaxWarnings != nil && *g.MaxWarnings < 0 {
+		return fmt.Errorf("%w: maxWarnings must not be negative", ErrInvalidOptions)
+	}
+	if g.MinScore != nil && (*g.MinScore < 0 || *g.MinScore > 100) {
+		return fmt.Errorf("%w: minScore must be between 0 and 100", ErrInvalidOptions)
+	}
+	for _, code := range g.ForbiddenCodes {
+		if strings.TrimSpace(code) == "" {
+			return fmt.Errorf("%w: forbiddenCodes must not contain empty codes", ErrInvalidOptions)
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the verdict of the gate for an analysis that finished with status and
+// result, along with the reasons it failed. An analysis that did not complete fails the gate.
+func (g *QualityGate) Evaluate(status AnalysisStatus, result []Issue) (GateStatus, []string) {
+	if status != StatusCompleted {
+		return GateFailed, []string{fmt.Sprintf("analysis %s", status)}
+	}
+
+	var reasons []string
+	counts := CountIssues(result)
+	if g.MaxErrors != nil && counts.Errors > *g.MaxErrors {
+		reasons = append(reasons, fmt.Sprintf("%d errors exceed the maximum of %d", counts.Errors, *g.MaxErrors))
+	}
+	if g.MaxWarnings != nil && counts.Warnings > *g.MaxWarnings {
+		reasons = append(reasons, fmt.Sprintf("%d warnings exceed the maximum of %d", counts.Warnings, *g.MaxWarnings))
+	}
+	if len(g.ForbiddenCodes) > 0 {
+		found := make(map[string]int)
+		for _, issue := range result {
+			for _, code := range g.ForbiddenCodes {
+				if MatchesCode(issue.Code, code) {
+					found[issue.Code]++
+					break
+				}
+			}
+		}
+		codes := make([]string, 0, len(found))
+		for code := range found {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			reasons = append(reasons, fmt.Sprintf("forbidden code %s found %d times", code, found[code]))
+		}
+	}
+	if g.MinScore != nil {
+		if score := Score(result); score < *g.MinScore {
+			reasons = append(reasons, fmt.Sprintf("score %d is below the minimum of %d", score, *g.MinScore))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return GateFailed, reasons
+	}
+	return GatePassed, nil
+}
+
+// MatchesCode reports whether an issue code is pattern or starts with pattern followed by a
+// dot, so that "WCAG2AA.Principle1.Guideline1_4.1_4_3" matches every code of that criterion.
+func MatchesCode(code, pattern string) bool {
+	return code == pattern || strings.HasPrefix(code, pattern+".")
+}
+
+// Score rates a result from 0 to 100. Every error costs 5 points and every warning 1 point;
+// notices are free.
+func Score(result []Issue) int {
+	counts := CountIssues(result)
+	return max(100-5*counts.Errors-counts.Warnings, 0)
+}
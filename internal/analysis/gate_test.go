@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestQualityGateValidate(t *testing.T) {
+	assert.NoError(t, (*QualityGate)(nil).Validate())
+	assert.NoError(t, (&QualityGate{MaxErrors: intPtr(0), MinScore: intPtr(100)}).Validate())
+	assert.ErrorIs(t, (&QualityGate{MaxErrors: intPtr(-1)}).Validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&QualityGate{MaxWarnings: intPtr(-1)}).Validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&QualityGate{MinScore: intPtr(101)}).Validate(), ErrInvalidOptions)
+	assert.ErrorIs(t, (&QualityGate{ForbiddenCodes: []string{" "}}).Validate(), ErrInvalidOptions)
+}
+
+func TestQualityGateEvaluate(t *testing.T) {
+	result := []Issue{
+		{Type: "error", Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail"},
+		{Type: "error", Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail"},
+		{Type: "error", Code: "image-alt"},
+		{Type: "warning", Code: "WCAG2AA.Principle1.Guideline1_3.1_3_1.H48"},
+		{Type: "notice", Code: "WCAG2AA.Principle2.Guideline2_4.2_4_2.H25.2"},
+	}
+	assert.Equal(t, 84, Score(result))
+
+	gate := &QualityGate{MaxErrors: intPtr(3), MaxWarnings: intPtr(1), MinScore: intPtr(80)}
+	status, reasons := gate.Evaluate(StatusCompleted, result)
+	assert.Equal(t, GatePassed, status)
+	assert.Empty(t, reasons)
+
+	gate = &QualityGate{
+		MaxErrors:      intPtr(0),
+		MaxWarnings:    intPtr(0),
+		ForbiddenCodes: []string{"WCAG2AA.Principle1.Guideline1_4.1_4_3", "image-alt", "WCAG2AA.Principle1.Guideline1_4.1_4"},
+		MinScore:       intPtr(90),
+	}
+	status, reasons = gate.Evaluate(StatusCompleted, result)
+	assert.Equal(t, GateFailed, status)
+	assert.Equal(t, []string{
+		"3 errors exceed the maximum of 0",
+		"1 warnings exceed the maximum of 0",
+		"forbidden code WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail found 2 times",
+		"forbidden code image-alt found 1 times",
+		"score 84 is below the minimum of 90",
+	}, reasons)
+
+	status, reasons = (&QualityGate{}).Evaluate(StatusFailed, nil)
+	assert.Equal(t, GateFailed, status)
+	assert.Equal(t, []string{"analysis failed"}, reasons)
+}
+
+func TestServiceEvaluatesQualityGate(t *testing.T) {
+	service := NewService(10)
+	gated, err := service.Create(Request{URL: "http://example.com", QualityGate: &QualityGate{MaxErrors: intPtr(0)}})
+	require.NoError(t, err)
+	plain, err := service.Create(Request{URL: "http://example.org"})
+	require.NoError(t, err)
+	assert.Empty(t, gated.Gate, "the gate is decided once the analysis finishes")
+
+	_, err = service.Create(Request{URL: "http://example.com", QualityGate: &QualityGate{MinScore: intPtr(-5)}})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	issues := []Issue{{Type: "error", Code: "image-alt"}}
+	service.UpdateResult(gated.ID, StatusCompleted, issues, "")
+	service.UpdateResult(plain.ID, StatusCompleted, issues, "")
+
+	a, _ := service.GetByID(gated.ID)
+	assert.Equal(t, GateFailed, a.Gate)
+	assert.Equal(t, []string{"1 errors exceed the maximum of 0"}, a.GateReasons)
+	a, _ = service.GetByID(plain.ID)
+	assert.Empty(t, a.Gate, "analyses without a gate get no verdict")
+
+	rerun, err := service.Rerun(gated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, *rerun.QualityGate.MaxErrors)
+	service.UpdateResult(rerun.ID, StatusCompleted, nil, "")
+	a, _ = service.GetByID(rerun.ID)
+	assert.Equal(t, GatePassed, a.Gate)
+}
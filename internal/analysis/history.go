@@ -0,0 +1,89 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+)
+
+// IssueCounts tallies issues by type.
+type IssueCounts struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Notices  int `json:"notices"`
+	Total    int `json:"total"`
+}
+
+// CountIssues tallies issues by their pa11y type.
+func CountIssues(issues []Issue) IssueCounts {
+	counts := IssueCounts{Total: len(issues)}
+	for _, issue := range issues {
+		switch issue.Type {
+		case "error":
+			counts.Errors++
+		case "warning":
+			counts.Warnings++
+		case "notice":
+			counts.Notices++
+		}
+	}
+	return counts
+}
+
+// HistoryEntry summarizes one run in the history of a URL.
+type HistoryEntry struct {
+	ID          string         `json:"id"`
+	Runner      string         `json:"runner,omitempty"`
+	Runners     []string       `json:"runners,omitempty"`
+	Status      AnalysisStatus `json:"status"`
+	PreviousID  string         `json:"previousId,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	CompletedAt time.Time      `json:"completedAt,omitempty"`
+	DurationMs  int64          `json:"durationMs,omitempty"`
+	Issues      IssueCounts    `json:"issues"`
+	Gate        GateStatus     `json:"gate,omitempty"`
+}
+
+// LatestCompleted returns the most recent completed run of each URL, sorted by URL, with its
+// credentials redacted. Earlier runs of a URL that was analyzed again are left out.
+func (s *Service) LatestCompleted() []*Analysis {
+	latest := make(map[string]*Analysis)
+	for _, a := range s.store.GetCompleted() {
+		if current, ok := latest[a.URL]; !ok || a.CreatedAt.After(current.CreatedAt) {
+			latest[a.URL] = a
+		}
+	}
+	analyses := make([]*Analysis, 0, len(latest))
+	for _, a := range latest {
+		analyses = append(analyses, a)
+	}
+	sort.Slice(analyses, func(i, j int) bool {
+		return analyses[i].URL < analyses[j].URL
+	})
+	return redactAll(analyses)
+}
+
+// History returns every run of url, oldest first, with its issue counts.
+func (s *Service) History(url string) []HistoryEntry {
+	entries := []HistoryEntry{}
+	for _, a := range s.store.GetAll() {
+		if a.URL != url {
+			continue
+		}
+		entries = append(entries, HistoryEntry{
+			ID:          a.ID,
+			Runner:      a.Runner,
+			Runners:     a.Runners,
+			Status:      a.Status,
+			PreviousID:  a.PreviousID,
+			CreatedAt:   a.CreatedAt,
+			CompletedAt: a.CompletedAt,
+			DurationMs:  a.DurationMs,
+			Issues:      CountIssues(a.Result),
+			Gate:        a.Gate,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries
+}
@@ -0,0 +1,55 @@
+package analysis
+
+import "slices"
+
+// MergeIssues de-duplicates the findings of several runners. Issues reported by different
+// runners for the same selector and WCAG success criterion are collapsed into one, keeping the
+// most severe of them and listing every reporting runner in Runners. Issues whose criterion is
+// unknown, and repeated findings of the same runner, are kept as they are. The order of first
+// appearance is preserved.
+func MergeIssues(issues []Issue) []Issue {
+	merged := make([]Issue, 0, len(issues))
+	byKey := make(map[string][]int)
+
+	for _, issue := range issues {
+		criterion := WCAGCriterion(issue)
+		if criterion == "" || issue.Selector == "" {
+			merged = append(merged, issue)
+			continue
+		}
+
+		key := issue.Selector + "\x00" + criterion
+		index := -1
+		for _, i := range byKey[key] {
+			if !slices.Contains(merged[i].Runners, issue.Runner) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			issue.Runners = []string{issue.Runner}
+			byKey[key] = append(byKey[key], len(merged))
+			merged = append(merged, issue)
+			continue
+		}
+
+		runners := append(merged[index].Runners, issue.Runner)
+		if moreSevere(issue, merged[index]) {
+			merged[index] = issue
+		}
+		merged[index].Runners = runners
+	}
+
+	// Only merged findings keep the list of runners.
+	for i := range merged {
+		if len(merged[i].Runners) < 2 {
+			merged[i].Runners = nil
+		}
+	}
+	return merged
+}
+
+// moreSevere reports whether a has a more severe type than b (error < warning < notice).
+func moreSevere(a, b Issue) bool {
+	return a.TypeCode > 0 && (b.TypeCode == 0 || a.TypeCode < b.TypeCode)
+}
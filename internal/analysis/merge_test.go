@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWCAGCriterion(t *testing.T) {
+	assert.Equal(t, "1.1.1", WCAGCriterion(Issue{Code: "WCAG2AA.Principle1.Guideline1_1.1_1_1.H37"}))
+	assert.Equal(t, "1.3.1", WCAGCriterion(Issue{Code: "WCAG2AA.Principle1.Guideline1_3.1_3_1_A.G141"}))
+	assert.Equal(t, "1.4.3", WCAGCriterion(Issue{Code: "color-contrast", Runner: "axe"}))
+	assert.Empty(t, WCAGCriterion(Issue{Code: "region"}))
+	assert.Equal(t, "1.4.12", WCAGCriterion(Issue{Code: "new-rule", RunnerExtras: map[string]interface{}{
+		"tags": []interface{}{"cat.color", "wcag2aa", "wcag1412"},
+	}}))
+}
+
+func TestGroupByCriterion(t *testing.T) {
+	issues := []Issue{
+		{Code: "region", Type: "notice"},
+		{Code: "WCAG2AA.Principle4.Guideline4_1.4_1_2.H91.A.EmptyNoId", Type: "error"},
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error"},
+		{Code: "color-contrast", Type: "warning"},
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_10.C32", Type: "warning"},
+		{Code: "WCAG2AA.Principle9.Guideline9_9.9_9_9.X1", Type: "error"},
+	}
+	groups := GroupByCriterion(issues)
+
+	var numbers []string
+	for _, g := range groups {
+		numbers = append(numbers, g.Criterion.Number)
+	}
+	assert.Equal(t, []string{"1.4.3", "1.4.10", "4.1.2", ""}, numbers)
+	assert.Equal(t, IssueCounts{Errors: 1, Warnings: 1, Total: 2}, groups[0].Counts)
+	assert.Equal(t, "AA", groups[0].Criterion.Level)
+	assert.Len(t, groups[3].Issues, 2, "unknown and unlisted criteria are grouped last")
+}
+
+func TestLookupCriterion(t *testing.T) {
+	c, ok := LookupCriterion("1.4.3")
+	assert.True(t, ok)
+	assert.Equal(t, "Contrast (Minimum)", c.Name)
+	assert.Equal(t, "AA", c.Level)
+	assert.Equal(t, "Perceivable", c.Principle())
+	assert.Equal(t, "https://www.w3.org/WAI/WCAG22/Understanding/contrast-minimum.html", c.URL())
+
+	_, ok = LookupCriterion("9.9.9")
+	assert.False(t, ok)
+	for rule, number := range axeCriteria {
+		_, ok := LookupCriterion(number)
+		assert.True(t, ok, "criterion %s of axe rule %s", number, rule)
+	}
+}
+
+func TestMergeIssues(t *testing.T) {
+	issues := []Issue{
+		{Code: "image-alt", Runner: "axe", Selector: "img", Type: "error", TypeCode: 1},
+		{Code: "region", Runner: "axe", Selector: "body > div", Type: "error", TypeCode: 1},
+		{Code: "WCAG2AA.Principle1.Guideline1_1.1_1_1.H37", Runner: "htmlcs", Selector: "img", Type: "error", TypeCode: 1},
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Runner: "htmlcs", Selector: "p", Type: "warning", TypeCode: 2},
+		{Code: "color-contrast", Runner: "axe", Selector: "p", Type: "error", TypeCode: 1},
+		{Code: "WCAG2AA.Principle1.Guideline1_1.1_1_1.H2.EG5", Runner: "htmlcs", Selector: "img", Type: "error", TypeCode: 1},
+	}
+
+	merged := MergeIssues(issues)
+	assert.Len(t, merged, 4)
+
+	// Both runners flagged the missing alt text; the first finding is kept.
+	assert.Equal(t, "image-alt", merged[0].Code)
+	assert.Equal(t, "axe", merged[0].Runner)
+	assert.Equal(t, []string{"axe", "htmlcs"}, merged[0].Runners)
+
+	// Findings without a known criterion are left alone.
+	assert.Equal(t, "region", merged[1].Code)
+	assert.Nil(t, merged[1].Runners)
+
+	// The more severe contrast finding wins.
+	assert.Equal(t, "color-contrast", merged[2].Code)
+	assert.Equal(t, []string{"htmlcs", "axe"}, merged[2].Runners)
+
+	// A second htmlcs finding on the same element is not a cross-runner duplicate.
+	assert.Equal(t, "WCAG2AA.Principle1.Guideline1_1.1_1_1.H2.EG5", merged[3].Code)
+	assert.Nil(t, merged[3].Runners)
+}
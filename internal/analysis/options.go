@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidOptions is returned when the pa11y options of an analysis are not valid.
+var ErrInvalidOptions = errors.New("invalid analysis options")
+
+// maxTimeoutMs caps the pa11y timeout a job may ask for.
+const maxTimeoutMs = 5 * 60 * 1000
+
+// Options holds the pa11y settings of an analysis. Zero values leave pa11y's defaults in place.
+type Options struct {
+	// Standard is the accessibility standard to test against: WCAG2A, WCAG2AA or WCAG2AAA.
+	Standard string `json:"standard,omitempty"`
+	// Level is the issue level at which pa11y reports a failure: error, warning, notice or none.
+	Level string `json:"level,omitempty"`
+	// Timeout is the maximum time in milliseconds a page may take to load and be tested.
+	Timeout int `json:"timeout,omitempty"`
+	// Wait is the time in milliseconds to wait after the page loads before testing it.
+	Wait int `json:"wait,omitempty"`
+	// RootElement is a CSS selector restricting the test to part of the page.
+	RootElement string `json:"rootElement,omitempty"`
+	// HideElements is a CSS selector of elements to leave out of the test.
+	HideElements string `json:"hideElements,omitempty"`
+	// Ignore lists issue types and codes to leave out of the results.
+	Ignore []string `json:"ignore,omitempty"`
+	// IncludeNotices adds notice-level issues to the results.
+	IncludeNotices bool `json:"includeNotices,omitempty"`
+	// IncludeWarnings adds warning-level issues to the results.
+	IncludeWarnings bool `json:"includeWarnings,omitempty"`
+	// Threshold is the number of issues tolerated before pa11y reports a failure.
+	Threshold int `json:"threshold,omitempty"`
+	// Viewport sets the browser window size. It is passed to pa11y through its config file.
+	Viewport *Viewport `json:"viewport,omitempty"`
+}
+
+// Viewport is the size of the browser window pages are tested in.
+type Viewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// maxViewportSize caps the width and height of a viewport.
+const maxViewportSize = 10000
+
+// Validate checks that both dimensions are positive and reasonable.
+func (v *Viewport) Validate() error {
+	if v == nil {
+		return nil
+	}
+	if v.Width < 1 || v.Width > maxViewportSize || v.Height < 1 || v.Height > maxViewportSize {
+		return fmt.Errorf("%w: viewport width and height must be between 1 and %d", ErrInvalidOptions, maxViewportSize)
+	}
+	return nil
+}
+
+var (
+	validStandards = []string{"WCAG2A", "WCAG2AA", "WCAG2AAA"}
+	validLevels    = []string{"error", "warning", "notice", "none"}
+)
+
+// Validate checks that the options can be handed to pa11y.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Standard != "" && !slices.Contains(validStandards, o.Standard) {
+		return fmt.Errorf("%w: standard must be one of %s", ErrInvalidOptions, strings.Join(validStandards, ", "))
+	}
+	if o.Level != "" && !slices.Contains(validLevels, o.Level) {
+		return fmt.Errorf("%w: level must be one of %s", ErrInvalidOptions, strings.Join(validLevels, ", "))
+	}
+	if o.Timeout < 0 || o.Timeout > maxTimeoutMs {
+		return fmt.Errorf("%w: timeout must be between 0 and %d ms", ErrInvalidOptions, maxTimeoutMs)
+	}
+	if o.Wait < 0 {
+		return fmt.Errorf("%w: wait must not be negative", ErrInvalidOptions)
+	}
+	if o.Timeout > 0 && o.Wait >= o.Timeout {
+		return fmt.Errorf("%w: wait must be shorter than timeout", ErrInvalidOptions)
+	}
+	if o.Threshold < 0 {
+		return fmt.Errorf("%w: threshold must not be negative", ErrInvalidOptions)
+	}
+	for _, selector := range []string{o.RootElement, o.HideElements} {
+		if strings.HasPrefix(strings.TrimSpace(selector), "-") {
+			return fmt.Errorf("%w: invalid selector %q", ErrInvalidOptions, selector)
+		}
+	}
+	for _, ignore := range o.Ignore {
+		if ignore == "" || strings.HasPrefix(ignore, "-") || strings.ContainsAny(ignore, " \t\n") {
+			return fmt.Errorf("%w: invalid ignore entry %q", ErrInvalidOptions, ignore)
+		}
+	}
+	return o.Viewport.Validate()
+}
+
+// Args translates the options into pa11y command-line flags.
+func (o *Options) Args() []string {
+	if o == nil {
+		return nil
+	}
+
+	var args []string
+	if o.Standard != "" {
+		args = append(args, "--standard", o.Standard)
+	}
+	if o.Level != "" {
+		args = append(args, "--level", o.Level)
+	}
+	if o.Timeout > 0 {
+		args = append(args, "--timeout", strconv.Itoa(o.Timeout))
+	}
+	if o.Wait > 0 {
+		args = append(args, "--wait", strconv.Itoa(o.Wait))
+	}
+	if o.RootElement != "" {
+		args = append(args, "--root-element", o.RootElement)
+	}
+	if o.HideElements != "" {
+		args = append(args, "--hide-elements", o.HideElements)
+	}
+	for _, ignore := range o.Ignore {
+		args = append(args, "--ignore", ignore)
+	}
+	if o.IncludeNotices {
+		args = append(args, "--include-notices")
+	}
+	if o.IncludeWarnings {
+		args = append(args, "--include-warnings")
+	}
+	if o.Threshold > 0 {
+		args = append(args, "--threshold", strconv.Itoa(o.Threshold))
+	}
+	return args
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsArgs(t *testing.T) {
+	options := &Options{
+		Standard:        "WCAG2AAA",
+		Timeout:         30000,
+		Wait:            500,
+		RootElement:     "main",
+		Ignore:          []string{"notice", "WCAG2AA.Principle3.Guideline3_1.3_1_1.H57.2"},
+		IncludeWarnings: true,
+	}
+
+	assert.NoError(t, options.Validate())
+	assert.Equal(t, []string{
+		"--standard", "WCAG2AAA",
+		"--timeout", "30000",
+		"--wait", "500",
+		"--root-element", "main",
+		"--ignore", "notice",
+		"--ignore", "WCAG2AA.Principle3.Guideline3_1.3_1_1.H57.2",
+		"--include-warnings",
+	}, options.Args())
+
+	var none *Options
+	assert.NoError(t, none.Validate())
+	assert.Empty(t, none.Args())
+}
+
+func TestOptionsValidate(t *testing.T) {
+	invalid := []*Options{
+		{Standard: "Section508"},
+		{Level: "fatal"},
+		{Timeout: -1},
+		{Timeout: maxTimeoutMs + 1},
+		{Timeout: 1000, Wait: 1000},
+		{Threshold: -5},
+		{RootElement: "--debug"},
+		{Ignore: []string{"notice warning"}},
+	}
+	for _, options := range invalid {
+		assert.ErrorIs(t, options.Validate(), ErrInvalidOptions, "%+v", options)
+	}
+}
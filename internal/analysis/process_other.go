@@ -0,0 +1,9 @@
+//go:build !unix
+
+package analysis
+
+import "os/exec"
+
+// killProcessGroupOnCancel leaves cmd as is: without process groups, cancelling its context
+// only kills pa11y itself.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
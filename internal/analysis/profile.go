@@ -0,0 +1,232 @@
+package analysis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrProfilesDisabled is returned when credential profiles are used without a server key.
+	ErrProfilesDisabled = errors.New("credential profiles are disabled")
+	// ErrProfileNotFound is returned when a credential profile does not exist.
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrProfileExists is returned when a credential profile with the same name already exists.
+	ErrProfileExists = errors.New("profile already exists")
+	// ErrInvalidProfile is returned when a credential profile is not valid.
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrProfileDecryption is returned when a stored profile cannot be decrypted, usually because
+	// PROFILES_KEY changed since it was created.
+	ErrProfileDecryption = errors.New("profile cannot be decrypted")
+)
+
+// ProfileStore persists sealed credential profiles by name.
+type ProfileStore interface {
+	// CreateProfile stores a new sealed profile. It returns ErrProfileExists if the name is taken.
+	CreateProfile(name string, sealed []byte) error
+	// GetProfile returns a sealed profile by its name.
+	GetProfile(name string) ([]byte, bool)
+	// ListProfiles returns every sealed profile keyed by name.
+	ListProfiles() map[string][]byte
+	// DeleteProfile removes a profile. It returns ErrProfileNotFound if it does not exist.
+	DeleteProfile(name string) error
+}
+
+// Profile is a named, reusable set of credentials and page settings for protected sites.
+// Analyses reference it by name instead of carrying the secrets themselves.
+type Profile struct {
+	Name string `json:"name"`
+	Auth
+	Viewport  *Viewport `json:"viewport,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Validate checks the profile name, credentials and viewport.
+func (p *Profile) Validate() error {
+	if !profileNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must be 1-64 letters, digits, dots, dashes or underscores", ErrInvalidProfile)
+	}
+	if err := p.Auth.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if err := p.Viewport.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the profile with every secret replaced.
+func (p *Profile) Redacted() *Profile {
+	c := *p
+	if redacted := p.Auth.Redacted(); redacted != nil {
+		c.Auth = *redacted
+	}
+	return &c
+}
+
+// ProfileService manages credential profiles, encrypting them with AES-GCM before they reach
+// the store. The profile name is bound to its ciphertext as additional data.
+type ProfileService struct {
+	store ProfileStore
+	aead  cipher.AEAD
+}
+
+// NewProfileService creates a profile service whose encryption key is derived from key.
+func NewProfileService(store ProfileStore, key string) (*ProfileService, error) {
+	if key == "" {
+		return nil, fmt.Errorf("%w: empty key", ErrProfilesDisabled)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile cipher: %w", err)
+	}
+	return &ProfileService{store: store, aead: aead}, nil
+}
+
+// Sealer encrypts the secrets of other services, such as webhook signing secrets, with the same
+// AES-GCM key as profiles, derived from PROFILES_KEY.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer creates a sealer whose encryption key is derived from key.
+func NewSealer(key string) (*Sealer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, binding it to id so that it cannot be swapped with another record.
+func (s *Sealer) Seal(plaintext []byte, id string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(id)), nil
+}
+
+// Open decrypts what Seal encrypted for id.
+func (s *Sealer) Open(sealed []byte, id string) ([]byte, error) {
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("sealed secret is too short")
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:size], sealed[size:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret (wrong PROFILES_KEY?): %w", err)
+	}
+	return plaintext, nil
+}
+
+// newAEAD creates the AES-GCM cipher whose key is derived from key.
+func newAEAD(key string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Create validates, encrypts and stores a new profile, returning it with its secrets redacted.
+func (s *ProfileService) Create(profile *Profile) (*Profile, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	profile.CreatedAt = time.Now()
+
+	sealed, err := s.seal(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateProfile(profile.Name, sealed); err != nil {
+		return nil, err
+	}
+	return profile.Redacted(), nil
+}
+
+// Get returns a profile by its name, with its secrets redacted. It returns ErrProfileNotFound if
+// it does not exist and ErrProfileDecryption if it cannot be decrypted with the server key.
+func (s *ProfileService) Get(name string) (*Profile, error) {
+	profile, err := s.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Redacted(), nil
+}
+
+// List returns every profile sorted by name, with their secrets redacted. Profiles that cannot be
+// decrypted are logged and left out.
+func (s *ProfileService) List() []*Profile {
+	profiles := []*Profile{}
+	for name, sealed := range s.store.ListProfiles() {
+		profile, err := s.open(name, sealed)
+		if err != nil {
+			log.Printf("Error decrypting profile %s: %v", name, err)
+			continue
+		}
+		profiles = append(profiles, profile.Redacted())
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// Delete removes a profile.
+func (s *ProfileService) Delete(name string) error {
+	return s.store.DeleteProfile(name)
+}
+
+// get returns a decrypted profile, including its secrets.
+func (s *ProfileService) get(name string) (*Profile, error) {
+	sealed, ok := s.store.GetProfile(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	profile, err := s.open(name, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	return profile, nil
+}
+
+// seal encrypts a profile as nonce || ciphertext.
+func (s *ProfileService) seal(profile *Profile) ([]byte, error) {
+	plaintext, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode profile %s: %w", profile.Name, err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(profile.Name)), nil
+}
+
+// open decrypts a profile sealed by seal.
+func (s *ProfileService) open(name string, sealed []byte) (*Profile, error) {
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("%w: sealed profile is too short", ErrProfileDecryption)
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:size], sealed[size:], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w (wrong PROFILES_KEY?): %v", ErrProfileDecryption, err)
+	}
+	var profile Profile
+	if err := json.Unmarshal(plaintext, &profile); err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %w", err)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testProfile() *Profile {
+	return &Profile{
+		Name:     "staging-login",
+		Auth:     Auth{Headers: map[string]string{"X-Api-Key": "s3cr3t-key"}, Actions: []string{"set field #password to s3cr3t-login"}},
+		Viewport: &Viewport{Width: 1280, Height: 800},
+	}
+}
+
+func TestProfilesEncryptedAtRest(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "analyses.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	profiles, err := NewProfileService(store, "server-key")
+	require.NoError(t, err)
+	created, err := profiles.Create(testProfile())
+	require.NoError(t, err)
+	assert.Equal(t, redactedValue, created.Headers["X-Api-Key"])
+
+	_, err = profiles.Create(testProfile())
+	assert.ErrorIs(t, err, ErrProfileExists)
+
+	sealed, ok := store.GetProfile("staging-login")
+	require.True(t, ok)
+	assert.False(t, bytes.Contains(sealed, []byte("s3cr3t")), "profiles must not be stored in clear text")
+
+	got, err := profiles.get("staging-login")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-key", got.Headers["X-Api-Key"])
+	require.Len(t, profiles.List(), 1)
+	assert.Equal(t, redactedValue, profiles.List()[0].Headers["X-Api-Key"])
+
+	// A different key cannot read the profiles, and says so.
+	other, err := NewProfileService(store, "another-key")
+	require.NoError(t, err)
+	_, err = other.Get("staging-login")
+	assert.ErrorIs(t, err, ErrProfileDecryption)
+	_, _, err = (&Service{profiles: other}).resolveProfile(&Analysis{Profile: "staging-login"})
+	assert.ErrorIs(t, err, ErrProfileDecryption)
+	_, err = profiles.Get("missing")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+
+	require.NoError(t, profiles.Delete("staging-login"))
+	assert.ErrorIs(t, profiles.Delete("staging-login"), ErrProfileNotFound)
+}
+
+func TestProfileValidate(t *testing.T) {
+	invalid := []*Profile{
+		{Name: ""},
+		{Name: "../etc"},
+		{Name: "ok", Auth: Auth{Actions: []string{"format disk"}}},
+		{Name: "ok", Viewport: &Viewport{Width: 0, Height: 800}},
+	}
+	for _, profile := range invalid {
+		assert.ErrorIs(t, profile.Validate(), ErrInvalidProfile, "%+v", profile)
+	}
+}
+
+func TestAnalysisWithProfile(t *testing.T) {
+	service := NewService(10)
+	_, err := service.Create(Request{URL: "http://example.com", Profile: "staging-login"})
+	assert.ErrorIs(t, err, ErrInvalidOptions, "profiles are disabled")
+
+	require.NoError(t, service.EnableProfiles("server-key"))
+	_, err = service.Profiles().Create(testProfile())
+	require.NoError(t, err)
+
+	_, err = service.Create(Request{URL: "http://example.com", Profile: "missing"})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+
+	a, err := service.Create(Request{
+		URL:     "http://example.com",
+		Profile: "staging-login",
+		Auth:    &Auth{Headers: map[string]string{"X-Api-Key": "override"}, Actions: []string{"click element #login"}},
+	})
+	require.NoError(t, err)
+
+	raw, _ := service.getWithSecrets(a.ID)
+	auth, options, err := service.resolveProfile(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "override", auth.Headers["X-Api-Key"])
+	assert.Equal(t, []string{"set field #password to s3cr3t-login", "click element #login"}, auth.Actions)
+	assert.Equal(t, &Viewport{Width: 1280, Height: 800}, options.Viewport)
+
+	require.NoError(t, service.Profiles().Delete("staging-login"))
+	_, _, err = service.resolveProfile(raw)
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
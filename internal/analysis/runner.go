@@ -1,6 +1,7 @@
 package analysis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,14 +9,58 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
 )
 
+// defaultRunner is the pa11y runner used when an analysis does not name one.
+const defaultRunner = "htmlcs"
+
+// pa11yWaitDelay is how long a cancelled pa11y run may take to release its output.
+const pa11yWaitDelay = 5 * time.Second
+
+// defaultPa11yConfig is the config file pa11y reads from its working directory when it is not
+// given one, e.g. /pa11y.json with the Chrome flags the Docker image needs.
+const defaultPa11yConfig = "pa11y.json"
+
+// RunnerList returns the runners of the analysis, falling back to the default runner.
+func (a *Analysis) RunnerList() []string {
+	if len(a.Runners) > 0 {
+		return a.Runners
+	}
+	if a.Runner != "" {
+		return []string{a.Runner}
+	}
+	return []string{defaultRunner}
+}
+
+// normalizeRunners trims, lower-cases and de-duplicates runner names, keeping their order.
+func normalizeRunners(runners []string) ([]string, error) {
+	var normalized []string
+	for _, runner := range runners {
+		runner = strings.ToLower(strings.TrimSpace(runner))
+		if runner == "" {
+			continue
+		}
+		if strings.HasPrefix(runner, "-") || strings.ContainsAny(runner, " \t\n") {
+			return nil, fmt.Errorf("%w: invalid runner %q", ErrInvalidOptions, runner)
+		}
+		if !slices.Contains(normalized, runner) {
+			normalized = append(normalized, runner)
+		}
+	}
+	return normalized, nil
+}
+
 // RunPa11y executes the pa11y command and returns the result.
-func RunPa11y(url string, runner string) ([]Issue, error) {
-	if runner == "" {
-		runner = "htmlcs"
+// All runners are executed in a single pa11y invocation; when there are several, their
+// findings are merged with MergeIssues. Credentials in auth and the viewport are passed through
+// a temporary pa11y config file, built on the base config file (see basePa11yConfigPath). The
+// pa11y process and the browsers it started are killed if ctx is cancelled before it exits.
+func RunPa11y(ctx context.Context, url string, runners []string, options *Options, auth *Auth) ([]Issue, error) {
+	if len(runners) == 0 {
+		runners = []string{defaultRunner}
 	}
 
 	// Determine the pa11y command to run, allowing override via PA11Y_COMMAND (e.g., "npx pa11y").
@@ -36,10 +81,35 @@ func RunPa11y(url string, runner string) ([]Issue, error) {
 	}
 
 	args := append([]string{}, baseArgs...)
-	args = append(args, "--reporter", "json", "--runner", runner, url)
+	args = append(args, "--reporter", "json")
+	for _, runner := range runners {
+		args = append(args, "--runner", runner)
+	}
+	args = append(args, options.Args()...)
+	config, err := pa11yConfig(options, auth)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		configPath, err := writePa11yConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(configPath)
+		args = append(args, "--config", configPath)
+	} else if basePath := basePa11yConfigPath(); basePath != "" {
+		args = append(args, "--config", basePath)
+	}
+	args = append(args, url)
 
-	cmd := exec.Command(execName, args...)
+	cmd := exec.CommandContext(ctx, execName, args...)
+	killProcessGroupOnCancel(cmd)
+	// Stop waiting for output still held by leftover processes once pa11y was killed.
+	cmd.WaitDelay = pa11yWaitDelay
 	output, err := cmd.CombinedOutput()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("pa11y run aborted: %w", ctxErr)
+	}
 	if err != nil {
 		// pa11y exits with code 2 if there are accessibility issues.
 		// We still want to see the JSON report in that case.
@@ -59,60 +129,113 @@ func RunPa11y(url string, runner string) ([]Issue, error) {
 		return nil, fmt.Errorf("error unmarshalling pa11y output: %v\nOutput was: %s", err, output)
 	}
 
+	if len(runners) > 1 {
+		result = MergeIssues(result)
+	}
 	return result, nil
 }
 
-// Worker processes analysis tasks from the queue.
-type Worker struct {
-	service *Service
-}
-
-// NewWorker creates a new worker.
-func NewWorker(service *Service) *Worker {
-	return &Worker{
-		service: service,
+// pa11yConfig returns the base pa11y config with the settings of the job that have no
+// command-line flag, or nil if there are none. They are passed to pa11y through a config file,
+// which also keeps secrets out of the process list. Since that file replaces the one pa11y
+// would read by default, everything else in the base config, such as chromeLaunchConfig, is kept.
+func pa11yConfig(options *Options, auth *Auth) (map[string]interface{}, error) {
+	var headers map[string]string
+	var actions []string
+	if auth != nil {
+		headers = auth.pa11yHeaders()
+		actions = auth.Actions
+	}
+	var viewport *Viewport
+	if options != nil {
+		viewport = options.Viewport
+	}
+	if len(headers) == 0 && len(actions) == 0 && viewport == nil {
+		return nil, nil
 	}
-}
 
-// Start begins the worker's processing loop.
-func (w *Worker) Start() {
-	go func() {
-		for {
-			analysisID := w.service.GetNextFromQueue()
-			analysis, ok := w.service.GetByID(analysisID)
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Error: analysis with ID %s not found\n", analysisID)
-				continue
+	config := map[string]interface{}{}
+	if path := basePa11yConfigPath(); path != "" {
+		base, err := loadPa11yConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		config = base
+	}
+	if len(headers) > 0 {
+		// Headers of the base config are kept unless the job sets them too.
+		merged := map[string]interface{}{}
+		if base, ok := config["headers"].(map[string]interface{}); ok {
+			for name, value := range base {
+				merged[name] = value
 			}
+		}
+		for name, value := range headers {
+			merged[name] = value
+		}
+		config["headers"] = merged
+	}
+	if len(actions) > 0 {
+		config["actions"] = actions
+	}
+	if viewport != nil {
+		config["viewport"] = viewport
+	}
+	return config, nil
+}
 
-			w.service.UpdateStatus(analysis.ID, StatusProcessing)
-
-			// Check URL reachability before running pa11y
-			if size, err := checkURLReachable(analysis.URL); err != nil {
-				fmt.Fprintf(os.Stderr, "URL not reachable %s: %v\n", analysis.URL, err)
-				w.service.UpdateResult(analysis.ID, StatusFailed, nil, fmt.Sprintf("URL not reachable: %v", err))
-				continue
-			} else {
-				w.service.UpdateSize(analysis.ID, size)
-			}
+// basePa11yConfigPath returns the pa11y config file named by PA11Y_CONFIG, or else
+// ./pa11y.json if it exists, or an empty string if there is none.
+func basePa11yConfigPath() string {
+	if path := strings.TrimSpace(os.Getenv("PA11Y_CONFIG")); path != "" {
+		return path
+	}
+	if _, err := os.Stat(defaultPa11yConfig); err == nil {
+		return defaultPa11yConfig
+	}
+	return ""
+}
 
-			// Use the specified runner if provided; RunPa11y defaults to htmlcs when empty
-			result, err := RunPa11y(analysis.URL, analysis.Runner)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error running pa11y for %s: %v\n", analysis.URL, err)
-				w.service.UpdateResult(analysis.ID, StatusFailed, nil, err.Error())
-				continue
-			}
+// loadPa11yConfig reads a pa11y JSON config file.
+func loadPa11yConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pa11y config: %v", err)
+	}
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing pa11y config %s: %v", path, err)
+	}
+	return config, nil
+}
 
-			w.service.UpdateResult(analysis.ID, StatusCompleted, result, "")
-		}
-	}()
+// writePa11yConfig writes config to a temporary file readable only by the current user
+// and returns its path.
+func writePa11yConfig(config map[string]interface{}) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("error encoding pa11y config: %v", err)
+	}
+	file, err := os.CreateTemp("", "pa11y-config-*.json")
+	if err != nil {
+		return "", fmt.Errorf("error creating pa11y config: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", fmt.Errorf("error writing pa11y config: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("error writing pa11y config: %v", err)
+	}
+	return file.Name(), nil
 }
 
 // checkURLReachable performs a direct GET request to verify reachability and returns the response size in bytes.
-// It validates the URL scheme (http/https), performs the request with a timeout,
+// It validates the URL scheme (http/https), performs the request with a timeout and the job's credentials,
 // and returns a descriptive error if the URL is not reachable or returns 4xx/5xx.
-func checkURLReachable(rawURL string) (int64, error) {
+func checkURLReachable(ctx context.Context, rawURL string, auth *Auth) (int64, error) {
 	// Validate URL
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -126,11 +249,12 @@ func checkURLReachable(rawURL string) (int64, error) {
 	}
 
 	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("User-Agent", "pa11y-go-wrapper/1.0")
+	auth.Apply(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
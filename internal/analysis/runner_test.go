@@ -0,0 +1,43 @@
+package analysis
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPa11yConfigKeepsBaseConfig(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "pa11y.json")
+	require.NoError(t, os.WriteFile(base, []byte(`{
+		"chromeLaunchConfig": {"args": ["--no-sandbox", "--disable-dev-shm-usage"]},
+		"headers": {"X-Env": "staging", "X-Api-Key": "base"}
+	}`), 0o600))
+	t.Setenv("PA11Y_CONFIG", base)
+
+	config, err := pa11yConfig(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, config, "pa11y reads the base config itself when the job adds nothing")
+	assert.Equal(t, base, basePa11yConfigPath())
+
+	config, err = pa11yConfig(&Options{Viewport: &Viewport{Width: 1280, Height: 1024}}, &Auth{Headers: map[string]string{"X-Api-Key": "job"}})
+	require.NoError(t, err)
+	path, err := writePa11yConfig(config)
+	require.NoError(t, err)
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, map[string]interface{}{"args": []interface{}{"--no-sandbox", "--disable-dev-shm-usage"}}, written["chromeLaunchConfig"])
+	assert.Equal(t, map[string]interface{}{"X-Env": "staging", "X-Api-Key": "job"}, written["headers"])
+	assert.Equal(t, map[string]interface{}{"width": 1280.0, "height": 1024.0}, written["viewport"])
+
+	t.Setenv("PA11Y_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	_, err = pa11yConfig(&Options{Viewport: &Viewport{Width: 1280, Height: 1024}}, nil)
+	assert.Error(t, err)
+}
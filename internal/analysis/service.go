@@ -1,6 +1,11 @@
 package analysis
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,6 +24,8 @@ const (
 	StatusCompleted AnalysisStatus = "completed"
 	// StatusFailed means the analysis failed.
 	StatusFailed AnalysisStatus = "failed"
+	// StatusCancelled means the analysis was cancelled before it could complete.
+	StatusCancelled AnalysisStatus = "cancelled"
 )
 
 // Issue represents a single accessibility issue.
@@ -31,6 +38,8 @@ type Issue struct {
 	Selector     string                 `json:"selector"`
 	Type         string                 `json:"type"`
 	TypeCode     int                    `json:"typeCode"`
+	// Runners lists every runner that reported the issue when findings of several runners were merged.
+	Runners []string `json:"runners,omitempty"`
 }
 
 // Analysis represents a single analysis task.
@@ -38,6 +47,11 @@ type Analysis struct {
 	ID           string         `json:"id"`
 	URL          string         `json:"url"`
 	Runner       string         `json:"runner,omitempty"`
+	Runners      []string       `json:"runners,omitempty"`
+	Options      *Options       `json:"options,omitempty"`
+	Auth         *Auth          `json:"auth,omitempty"`
+	Profile      string         `json:"profile,omitempty"`
+	QualityGate  *QualityGate   `json:"qualityGate,omitempty"`
 	Status       AnalysisStatus `json:"status"`
 	Result       []Issue        `json:"result,omitempty"`
 	ErrorMessage string         `json:"errorMessage,omitempty"`
@@ -47,153 +61,481 @@ type Analysis struct {
 	StartedAt    time.Time      `json:"startedAt,omitempty"`
 	CompletedAt  time.Time      `json:"completedAt,omitempty"`
 	DurationMs   int64          `json:"durationMs,omitempty"`
+	PreviousID   string         `json:"previousId,omitempty"`
+	// Gate is the verdict of the quality gate, set once an analysis with a gate finishes.
+	Gate        GateStatus `json:"gate,omitempty"`
+	GateReasons []string   `json:"gateReasons,omitempty"`
 }
 
+var (
+	// ErrQueueFull is returned when an analysis cannot be admitted because the queue is full.
+	ErrQueueFull = errors.New("analysis queue is full")
+	// ErrNotFound is returned when an analysis does not exist.
+	ErrNotFound = errors.New("analysis not found")
+	// ErrInvalidState is returned when an operation is not allowed in the analysis' current status.
+	ErrInvalidState = errors.New("operation not allowed in the current analysis status")
+)
+
 // Service provides operations for managing analysis tasks.
 type Service struct {
-	mu       sync.RWMutex
-	analyses map[string]*Analysis
-	queue    chan string
+	store Store
+	queue chan string
+
+	// mu guards admission to the queue and status transitions that race with the workers;
+	// reads go straight to the store.
+	mu sync.Mutex
+	// overflow holds the IDs of pending analyses that did not fit in the queue, in admission order.
+	overflow        []string
+	overflowEnabled bool
+	// pool is the worker pool whose limits direct analyses share, or nil.
+	pool *WorkerPool
+	// parked is the number of analyses a WorkerPool took off the queue but parked until their
+	// host frees up. They still count toward the queue size.
+	parked int
+	// running holds the cancel functions of the analyses being processed.
+	running map[string]context.CancelFunc
+	// profiles is nil unless credential profiles are enabled.
+	profiles *ProfileService
+	events   *Broker
 }
 
-// NewService creates a new analysis service.
+// NewService creates a new analysis service backed by an in-memory store.
 func NewService(queueSize int) *Service {
+	return NewServiceWithStore(NewMemoryStore(), queueSize)
+}
+
+// NewServiceWithStore creates a new analysis service backed by the given store.
+func NewServiceWithStore(store Store, queueSize int) *Service {
 	return &Service{
-		analyses: make(map[string]*Analysis),
-		queue:    make(chan string, queueSize),
+		store:   store,
+		queue:   make(chan string, queueSize),
+		running: make(map[string]context.CancelFunc),
+		events:  NewBroker(),
 	}
 }
 
-// Create new analysis task and add it to the queue.
-func (s *Service) Create(url string, runner string) *Analysis {
+// Events returns the broker on which the service publishes analysis events.
+func (s *Service) Events() *Broker {
+	return s.events
+}
+
+// EnableOverflow lets the service accept analyses beyond the queue size. Analyses that do
+// not fit stay pending in the store and are moved onto the queue as workers free up room.
+func (s *Service) EnableOverflow() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.overflowEnabled = true
+}
+
+// EnableProfiles turns on credential profiles, encrypting them with key. The service's store
+// must also implement ProfileStore; if it is a SecretSealer, the inline credentials of analyses
+// are encrypted with key too. It must be called before the service is used.
+func (s *Service) EnableProfiles(key string) error {
+	store, ok := s.store.(ProfileStore)
+	if !ok {
+		return fmt.Errorf("%w: the analysis store cannot hold profiles", ErrProfilesDisabled)
+	}
+	profiles, err := NewProfileService(store, key)
+	if err != nil {
+		return err
+	}
+	if sealer, ok := s.store.(SecretSealer); ok {
+		if err := sealer.SealSecrets(key); err != nil {
+			return err
+		}
+	}
+	s.profiles = profiles
+	return nil
+}
+
+// Profiles returns the credential profile service, or nil if profiles are disabled.
+func (s *Service) Profiles() *ProfileService {
+	return s.profiles
+}
+
+// Request describes a page to analyze and how to analyze it.
+type Request struct {
+	URL string
+	// Runners lists the pa11y runners to execute. Empty uses the default runner; with several
+	// runners their findings are merged into a single result.
+	Runners []string
+	Options *Options
+	Auth    *Auth
+	// Profile names a credential profile applied when the analysis runs.
+	Profile string
+	// QualityGate sets the limits the result is checked against.
+	QualityGate *QualityGate
+}
+
+// Create new analysis task and add it to the queue.
+// It returns ErrInvalidOptions if the runners, options, credentials, profile or quality gate are
+// not valid, and ErrQueueFull if the queue is full and overflow is disabled.
+func (s *Service) Create(req Request) (*Analysis, error) {
+	analysis, err := s.newAnalysis(req)
+	if err != nil {
+		return nil, err
+	}
+	analysis, err = s.admit(analysis)
+	return analysis.redacted(), err
+}
+
+// newAnalysis validates req and turns it into an analysis that is not stored yet.
+func (s *Service) newAnalysis(req Request) (*Analysis, error) {
+	runners, err := normalizeRunners(req.Runners)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Options.Validate(); err != nil {
+		return nil, err
+	}
+	if err := req.Auth.Validate(); err != nil {
+		return nil, err
+	}
+	if err := req.QualityGate.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Profile != "" {
+		if s.profiles == nil {
+			return nil, fmt.Errorf("%w: profile %q: %v", ErrInvalidOptions, req.Profile, ErrProfilesDisabled)
+		}
+		if _, err := s.profiles.Get(req.Profile); errors.Is(err, ErrProfileNotFound) {
+			return nil, fmt.Errorf("%w: unknown profile %q", ErrInvalidOptions, req.Profile)
+		} else if err != nil {
+			return nil, err
+		}
+	}
 
-	id := uuid.New().String()
 	analysis := &Analysis{
-		ID:        id,
-		URL:       url,
-		Runner:    runner,
-		Status:    StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		URL:         req.URL,
+		Options:     req.Options,
+		Auth:        req.Auth,
+		Profile:     req.Profile,
+		QualityGate: req.QualityGate,
+	}
+	if len(runners) == 1 {
+		analysis.Runner = runners[0]
+	} else {
+		analysis.Runners = runners
+	}
+	return analysis, nil
+}
+
+// Rerun queues a new analysis of the same page with the same settings as an existing one,
+// linking it to the original through PreviousID. It returns ErrNotFound if the analysis does
+// not exist and ErrInvalidState if it has not finished yet.
+func (s *Service) Rerun(id string) (*Analysis, error) {
+	previous, ok := s.store.GetByID(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if previous.Status == StatusPending || previous.Status == StatusProcessing {
+		return nil, ErrInvalidState
 	}
 
-	s.analyses[analysis.ID] = analysis
-	s.queue <- analysis.ID
-	return analysis
+	analysis, err := s.admit(&Analysis{
+		URL:         previous.URL,
+		Runner:      previous.Runner,
+		Runners:     previous.Runners,
+		Options:     previous.Options,
+		Auth:        previous.Auth,
+		Profile:     previous.Profile,
+		QualityGate: previous.QualityGate,
+		PreviousID:  previous.ID,
+	})
+	return analysis.redacted(), err
 }
 
-// GetAll returns all analysis tasks.
-func (s *Service) GetAll() []*Analysis {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// admit stores analysis as a new pending task and adds it to the queue.
+func (s *Service) admit(analysis *Analysis) (*Analysis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	analyses := make([]*Analysis, 0, len(s.analyses))
-	for _, analysis := range s.analyses {
-		analyses = append(analyses, analysis)
+	if !s.overflowEnabled && s.fullLocked() {
+		return nil, ErrQueueFull
 	}
-	return analyses
+
+	analysis.ID = uuid.New().String()
+	analysis.Status = StatusPending
+	analysis.CreatedAt = time.Now()
+	analysis.UpdatedAt = analysis.CreatedAt
+
+	if err := s.store.Create(analysis); err != nil {
+		return nil, err
+	}
+	s.enqueueLocked(analysis.ID)
+	s.events.Publish(newEvent(EventCreated, analysis))
+	return analysis, nil
 }
 
-// GetCompleted returns all completed analysis tasks.
-func (s *Service) GetCompleted() []*Analysis {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// RequeuePending puts every pending or processing analysis found in the store back on the queue,
+// oldest first. It is meant to be called once at startup so that jobs interrupted by a restart are
+// not lost; analyses that do not fit in the queue overflow regardless of EnableOverflow. Without
+// overflow, new analyses are then rejected until the recovered ones have entered the queue, so
+// that they cannot jump ahead of them. It returns the number of re-enqueued analyses.
+func (s *Service) RequeuePending() int {
+	var requeue []*Analysis
+	for _, analysis := range s.store.GetAll() {
+		if analysis.Status == StatusPending || analysis.Status == StatusProcessing {
+			requeue = append(requeue, analysis)
+		}
+	}
+	sort.Slice(requeue, func(i, j int) bool {
+		return requeue[i].CreatedAt.Before(requeue[j].CreatedAt)
+	})
 
-	analyses := make([]*Analysis, 0, len(s.analyses))
-	for _, analysis := range s.analyses {
-		if analysis.Status == StatusCompleted {
-			analyses = append(analyses, analysis)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, analysis := range requeue {
+		if analysis.Status == StatusProcessing {
+			// The previous run was interrupted; start over.
+			s.UpdateStatus(analysis.ID, StatusPending)
 		}
+		s.enqueueLocked(analysis.ID)
 	}
-	return analyses
+	return len(requeue)
 }
 
-// GetByID returns an analysis task by its ID.
-func (s *Service) GetByID(id string) (*Analysis, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// fullLocked reports whether the queue has no room for another analysis: analyses parked by the
+// workers count toward its size, and overflowing analyses must enter it first. s.mu must be held.
+func (s *Service) fullLocked() bool {
+	return len(s.overflow) > 0 || len(s.queue)+s.parked >= cap(s.queue)
+}
 
-	analysis, ok := s.analyses[id]
-	return analysis, ok
+// parkedChanged adjusts the number of analyses parked by the workers by delta.
+func (s *Service) parkedChanged(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parked += delta
 }
 
-// GetNextFromQueue gets the next analysis ID from the queue. This will block if the queue is empty.
-func (s *Service) GetNextFromQueue() string {
-	return <-s.queue
+// enqueueLocked pushes id onto the queue, or onto the overflow when the queue is full
+// or older analyses are already waiting there. s.mu must be held.
+func (s *Service) enqueueLocked(id string) {
+	if len(s.overflow) == 0 {
+		select {
+		case s.queue <- id:
+			return
+		default:
+		}
+	}
+	s.overflow = append(s.overflow, id)
 }
 
-// UpdateStatus updates the status of an analysis task.
-func (s *Service) UpdateStatus(id string, status AnalysisStatus) {
+// refillQueue moves overflowing analyses onto the queue while it has room.
+func (s *Service) refillQueue() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if analysis, ok := s.analyses[id]; ok {
-		now := time.Now()
-		// Transition-specific timestamps
-		if status == StatusProcessing {
-			if analysis.StartedAt.IsZero() {
-				analysis.StartedAt = now
-			}
+	for len(s.overflow) > 0 {
+		select {
+		case s.queue <- s.overflow[0]:
+			s.overflow = s.overflow[1:]
+		default:
+			return
 		}
-		if status == StatusCompleted || status == StatusFailed {
-			if analysis.CompletedAt.IsZero() {
-				analysis.CompletedAt = now
-			}
-			// Compute duration from StartedAt if available, otherwise from CreatedAt
-			start := analysis.StartedAt
-			if start.IsZero() {
-				start = analysis.CreatedAt
-			}
-			dur := analysis.CompletedAt.Sub(start)
-			if dur < 0 {
-				dur = 0
-			}
-			analysis.DurationMs = dur.Milliseconds()
+	}
+}
+
+// Cancel stops an analysis. A pending analysis is skipped when it reaches a worker; a processing
+// one has its pa11y run killed. It returns ErrNotFound if the analysis does not exist and
+// ErrInvalidState if it already finished.
+func (s *Service) Cancel(id string) (*Analysis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, ok := s.store.GetByID(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	switch analysis.Status {
+	case StatusPending:
+		s.removeOverflowLocked(id)
+	case StatusProcessing:
+		if cancel, ok := s.running[id]; ok {
+			cancel()
+			delete(s.running, id)
 		}
+	default:
+		return nil, ErrInvalidState
+	}
 
-		analysis.Status = status
-		analysis.UpdatedAt = now
+	if err := s.store.UpdateResult(id, StatusCancelled, nil, "cancelled by user"); err != nil {
+		return nil, err
 	}
+	analysis, _ = s.store.GetByID(id)
+	s.events.Publish(newEvent(EventStatus, analysis))
+	return analysis.redacted(), nil
 }
 
-// UpdateResult updates the result of an analysis task.
-func (s *Service) UpdateResult(id string, status AnalysisStatus, result []Issue, errorMessage string) {
+// Delete removes an analysis. Pending analyses are taken off the queue; processing ones
+// must be cancelled first. It returns ErrNotFound if the analysis does not exist and
+// ErrInvalidState if it is being processed.
+func (s *Service) Delete(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if analysis, ok := s.analyses[id]; ok {
-		now := time.Now()
-		analysis.Status = status
-		analysis.Result = result
-		analysis.ErrorMessage = errorMessage
-
-		if status == StatusCompleted || status == StatusFailed {
-			if analysis.CompletedAt.IsZero() {
-				analysis.CompletedAt = now
-			}
-			start := analysis.StartedAt
-			if start.IsZero() {
-				start = analysis.CreatedAt
-			}
-			dur := analysis.CompletedAt.Sub(start)
-			if dur < 0 {
-				dur = 0
-			}
-			analysis.DurationMs = dur.Milliseconds()
+	analysis, ok := s.store.GetByID(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if analysis.Status == StatusProcessing {
+		return ErrInvalidState
+	}
+	s.removeOverflowLocked(id)
+	// IDs already in the queue channel are skipped by the workers once the analysis is gone.
+	if err := s.store.Delete(id); err != nil {
+		return err
+	}
+	s.events.Publish(newEvent(EventDeleted, analysis))
+	return nil
+}
+
+// removeOverflowLocked drops id from the overflow, if present. s.mu must be held.
+func (s *Service) removeOverflowLocked(id string) {
+	for i, queued := range s.overflow {
+		if queued == id {
+			s.overflow = append(s.overflow[:i:i], s.overflow[i+1:]...)
+			return
 		}
+	}
+}
 
-		analysis.UpdatedAt = now
+// startProcessing moves a pending analysis to processing and returns a context derived from
+// parent that is also cancelled by Cancel. It returns false if the analysis is no longer
+// pending, e.g. because it was cancelled or deleted while waiting in the queue.
+func (s *Service) startProcessing(parent context.Context, id string) (context.Context, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, ok := s.store.GetByID(id)
+	if !ok || analysis.Status != StatusPending {
+		return nil, false
 	}
+	ctx, cancel := context.WithCancel(parent)
+	s.running[id] = cancel
+	s.UpdateStatus(id, StatusProcessing)
+	return ctx, true
 }
 
-// UpdateSize updates the fetched size of the target URL in bytes.
-func (s *Service) UpdateSize(id string, size int64) {
+// finishProcessing records the outcome of a run begun with startProcessing.
+// Outcomes of runs cancelled in the meantime are discarded.
+func (s *Service) finishProcessing(id string, status AnalysisStatus, result []Issue, errorMessage string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if analysis, ok := s.analyses[id]; ok {
-		analysis.SizeBytes = size
-		analysis.UpdatedAt = time.Now()
+
+	cancel, ok := s.running[id]
+	if !ok {
+		return
+	}
+	cancel()
+	delete(s.running, id)
+	s.UpdateResult(id, status, result, errorMessage)
+}
+
+// GetAll returns all analysis tasks, with their credentials redacted.
+func (s *Service) GetAll() []*Analysis {
+	return redactAll(s.store.GetAll())
+}
+
+// GetCompleted returns all completed analysis tasks, with their credentials redacted.
+func (s *Service) GetCompleted() []*Analysis {
+	return redactAll(s.store.GetCompleted())
+}
+
+// GetByID returns an analysis task by its ID, with its credentials redacted.
+func (s *Service) GetByID(id string) (*Analysis, bool) {
+	analysis, ok := s.store.GetByID(id)
+	return analysis.redacted(), ok
+}
+
+// getWithSecrets returns an analysis task by its ID, including its credentials.
+// Only the workers may use it.
+func (s *Service) getWithSecrets(id string) (*Analysis, bool) {
+	return s.store.GetByID(id)
+}
+
+// resolveProfile returns the credentials and options to run an analysis with, applying its
+// credential profile if it names one. The analysis' own credentials take precedence over the
+// profile's, and so does its own viewport.
+func (s *Service) resolveProfile(analysis *Analysis) (*Auth, *Options, error) {
+	if analysis.Profile == "" {
+		return analysis.Auth, analysis.Options, nil
+	}
+	if s.profiles == nil {
+		return nil, nil, ErrProfilesDisabled
+	}
+	profile, err := s.profiles.get(analysis.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := analysis.Options
+	if profile.Viewport != nil && (options == nil || options.Viewport == nil) {
+		withViewport := Options{}
+		if options != nil {
+			withViewport = *options
+		}
+		withViewport.Viewport = profile.Viewport
+		options = &withViewport
+	}
+	return profile.Auth.merge(analysis.Auth), options, nil
+}
+
+// redacted returns a copy of the analysis with its credentials redacted,
+// or the analysis itself if it has none.
+func (a *Analysis) redacted() *Analysis {
+	if a == nil || a.Auth == nil {
+		return a
+	}
+	c := *a
+	c.Auth = a.Auth.Redacted()
+	return &c
+}
+
+func redactAll(analyses []*Analysis) []*Analysis {
+	for i, a := range analyses {
+		analyses[i] = a.redacted()
+	}
+	return analyses
+}
+
+// GetNextFromQueue gets the next analysis ID from the queue. This will block if the queue is empty.
+func (s *Service) GetNextFromQueue() string {
+	id := <-s.queue
+	s.refillQueue()
+	return id
+}
+
+// UpdateStatus updates the status of an analysis task and publishes an EventStatus.
+func (s *Service) UpdateStatus(id string, status AnalysisStatus) {
+	if err := s.store.UpdateStatus(id, status); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating status of analysis %s: %v\n", id, err)
+		return
+	}
+	s.publish(EventStatus, id)
+}
+
+// UpdateResult updates the result of an analysis task and publishes an EventStatus.
+func (s *Service) UpdateResult(id string, status AnalysisStatus, result []Issue, errorMessage string) {
+	if err := s.store.UpdateResult(id, status, result, errorMessage); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating result of analysis %s: %v\n", id, err)
+		return
+	}
+	s.publish(EventStatus, id)
+}
+
+// UpdateSize updates the fetched size of the target URL in bytes and publishes an EventSize.
+func (s *Service) UpdateSize(id string, size int64) {
+	if err := s.store.UpdateSize(id, size); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating size of analysis %s: %v\n", id, err)
+		return
+	}
+	s.publish(EventSize, id)
+}
+
+// publish sends an event of type t carrying the current state of analysis id.
+func (s *Service) publish(t EventType, id string) {
+	if analysis, ok := s.store.GetByID(id); ok {
+		s.events.Publish(newEvent(t, analysis))
 	}
 }
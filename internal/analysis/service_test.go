@@ -0,0 +1,323 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFailsFastWhenQueueIsFull(t *testing.T) {
+	service := NewService(1)
+
+	_, err := service.Create(Request{URL: "http://example.com/1"})
+	require.NoError(t, err)
+	_, err = service.Create(Request{URL: "http://example.com/2"})
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Len(t, service.GetAll(), 1, "rejected analyses are not stored")
+}
+
+func TestCreateOverflow(t *testing.T) {
+	service := NewService(1)
+	service.EnableOverflow()
+
+	var ids []string
+	for _, url := range []string{"http://example.com/1", "http://example.com/2", "http://example.com/3"} {
+		a, err := service.Create(Request{URL: url})
+		require.NoError(t, err)
+		ids = append(ids, a.ID)
+	}
+
+	// Overflowing analyses are handed out in admission order.
+	for _, id := range ids {
+		assert.Equal(t, id, service.GetNextFromQueue())
+	}
+}
+
+func TestCreateCountsParkedAnalyses(t *testing.T) {
+	t.Setenv("PA11Y_COMMAND", "false")
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+
+	service := NewService(2)
+	pool := NewWorkerPool(service, 3, 1)
+	pool.Start()
+	t.Cleanup(func() {
+		// Let the workers finish before the next test changes PA11Y_COMMAND.
+		close(release)
+		assert.Eventually(t, func() bool { return pool.Stats().Busy == 0 && pool.Stats().Waiting == 0 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	// The first analysis holds the only slot for the host; the next two are parked.
+	for i := 0; i < 3; i++ {
+		_, err := service.Create(Request{URL: server.URL})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return len(service.queue) == 0 }, time.Second, time.Millisecond)
+	}
+	require.Eventually(t, func() bool { return pool.Stats().Waiting == 2 }, time.Second, time.Millisecond)
+
+	_, err := service.Create(Request{URL: server.URL})
+	assert.ErrorIs(t, err, ErrQueueFull, "parked analyses fill the queue")
+}
+
+func TestRequeuedAnalysesGoFirst(t *testing.T) {
+	store := NewMemoryStore()
+	var ids []string
+	for i := 0; i < 3; i++ {
+		a := &Analysis{ID: fmt.Sprintf("recovered-%d", i), URL: "http://example.com", Status: StatusPending, CreatedAt: time.Now()}
+		require.NoError(t, store.Create(a))
+		ids = append(ids, a.ID)
+	}
+	service := NewServiceWithStore(store, 1)
+	assert.Equal(t, 3, service.RequeuePending())
+
+	_, err := service.Create(Request{URL: "http://example.com/new"})
+	assert.ErrorIs(t, err, ErrQueueFull, "new analyses wait for the recovered ones")
+
+	for _, id := range ids {
+		assert.Equal(t, id, service.GetNextFromQueue())
+	}
+	a, err := service.Create(Request{URL: "http://example.com/new"})
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, service.GetNextFromQueue())
+}
+
+func TestCancelPending(t *testing.T) {
+	service := NewService(10)
+	a, err := service.Create(Request{URL: "http://example.com"})
+	require.NoError(t, err)
+
+	cancelled, err := service.Cancel(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+
+	_, ok := service.startProcessing(context.Background(), a.ID)
+	assert.False(t, ok, "cancelled analyses are skipped by the workers")
+
+	_, err = service.Cancel(a.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+// processAlive reports whether a process is running, reading its state from /proc so that an
+// exited process waiting to be reaped does not count.
+func processAlive(t *testing.T, pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	require.NoError(t, err)
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return fields[0] != "Z"
+}
+
+func TestCancelProcessing(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc to check the pa11y process tree")
+	}
+	// A fake pa11y that starts a child, like the browser pa11y launches, and waits for it.
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	script := filepath.Join(dir, "pa11y")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nsleep 60 &\necho $! > "+pidFile+"\nwait\n"), 0o755))
+	t.Setenv("PA11Y_COMMAND", script)
+
+	service := NewService(10)
+	a, err := service.Create(Request{URL: "http://example.com"})
+	require.NoError(t, err)
+
+	ctx, ok := service.startProcessing(context.Background(), a.ID)
+	require.True(t, ok)
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunPa11y(ctx, a.URL, nil, nil, nil)
+		done <- err
+	}()
+	var childPID int
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(pidFile)
+		if err != nil {
+			return false
+		}
+		childPID, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = service.Cancel(a.ID)
+	require.NoError(t, err)
+	assert.Error(t, ctx.Err(), "the run context is cancelled")
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(pa11yWaitDelay):
+		t.Fatal("RunPa11y did not return after the run was cancelled")
+	}
+	assert.Eventually(t, func() bool { return !processAlive(t, childPID) }, time.Second, 10*time.Millisecond,
+		"the processes started by pa11y are killed too")
+
+	// The late outcome of the killed run does not overwrite the cancellation.
+	service.finishProcessing(a.ID, StatusFailed, nil, "signal: killed")
+	got, _ := service.GetByID(a.ID)
+	assert.Equal(t, StatusCancelled, got.Status)
+}
+
+// cancellingStore cancels every analysis as soon as it is created, like a Cancel request landing
+// before a direct analysis starts.
+type cancellingStore struct {
+	*MemoryStore
+}
+
+func (s cancellingStore) Create(analysis *Analysis) error {
+	if err := s.MemoryStore.Create(analysis); err != nil {
+		return err
+	}
+	return s.UpdateStatus(analysis.ID, StatusCancelled)
+}
+
+func TestAnalyzeCancelledBeforeStart(t *testing.T) {
+	service := NewServiceWithStore(cancellingStore{NewMemoryStore()}, 10)
+
+	a, err := service.Analyze(context.Background(), Request{URL: "http://example.com"}, true)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	require.NotNil(t, a)
+	assert.Equal(t, StatusCancelled, a.Status)
+}
+
+func TestAnalyzeSharesWorkerPoolLimits(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "pa11y")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho '[]'\n"), 0o755))
+	t.Setenv("PA11Y_COMMAND", script)
+	entered := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	other := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	service := NewService(10)
+	pool := NewWorkerPool(service, 2, 1)
+	pool.Start()
+	service.SetWorkerPool(pool)
+
+	analyze := func(url string) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			_, err := service.Analyze(context.Background(), Request{URL: url}, false)
+			done <- err
+		}()
+		<-entered
+		return done
+	}
+	first := analyze(server.URL)
+
+	_, err := service.Analyze(context.Background(), Request{URL: server.URL}, false)
+	assert.ErrorIs(t, err, ErrBusy, "the host is saturated")
+	second := analyze(other)
+	_, err = service.Analyze(context.Background(), Request{URL: "http://example.com"}, false)
+	assert.ErrorIs(t, err, ErrBusy, "every direct slot is taken")
+
+	// A queued analysis of the saturated host waits for the direct one, then runs.
+	queued, err := service.Create(Request{URL: server.URL})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return pool.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	close(release)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	assert.Eventually(t, func() bool {
+		a, _ := service.GetByID(queued.ID)
+		return a.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, err := service.Analyze(context.Background(), Request{URL: server.URL}, false)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "the slots are released")
+}
+
+func TestDelete(t *testing.T) {
+	service := NewService(10)
+	a, err := service.Create(Request{URL: "http://example.com"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Delete(a.ID))
+	_, ok := service.GetByID(a.ID)
+	assert.False(t, ok)
+	assert.ErrorIs(t, service.Delete(a.ID), ErrNotFound)
+
+	b, err := service.Create(Request{URL: "http://example.org"})
+	require.NoError(t, err)
+	_, ok = service.startProcessing(context.Background(), b.ID)
+	require.True(t, ok)
+	assert.ErrorIs(t, service.Delete(b.ID), ErrInvalidState, "running analyses must be cancelled first")
+}
+
+func TestRerunAndHistory(t *testing.T) {
+	service := NewService(10)
+	first, err := service.Create(Request{URL: "http://example.com", Runners: []string{"axe"}})
+	require.NoError(t, err)
+
+	_, err = service.Rerun(first.ID)
+	assert.ErrorIs(t, err, ErrInvalidState, "pending analyses cannot be re-run")
+
+	service.UpdateResult(first.ID, StatusCompleted, []Issue{{Type: "error"}, {Type: "error"}, {Type: "notice"}}, "")
+	second, err := service.Rerun(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.URL, second.URL)
+	assert.Equal(t, "axe", second.Runner)
+	assert.Equal(t, first.ID, second.PreviousID)
+	service.UpdateResult(second.ID, StatusCompleted, []Issue{{Type: "error"}}, "")
+
+	_, err = service.Create(Request{URL: "http://example.org"})
+	require.NoError(t, err)
+
+	history := service.History("http://example.com")
+	require.Len(t, history, 2)
+	assert.Equal(t, first.ID, history[0].ID)
+	assert.Equal(t, IssueCounts{Errors: 2, Notices: 1, Total: 3}, history[0].Issues)
+	assert.Equal(t, second.ID, history[1].ID)
+	assert.Equal(t, 1, history[1].Issues.Errors)
+
+	_, err = service.Rerun("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCreateWithRunners(t *testing.T) {
+	service := NewService(10)
+
+	single, err := service.Create(Request{URL: "http://example.com", Runners: []string{" AXE "}})
+	require.NoError(t, err)
+	assert.Equal(t, "axe", single.Runner)
+	assert.Equal(t, []string{"axe"}, single.RunnerList())
+
+	multi, err := service.Create(Request{URL: "http://example.com", Runners: []string{"axe", "htmlcs", "axe"}})
+	require.NoError(t, err)
+	assert.Empty(t, multi.Runner)
+	assert.Equal(t, []string{"axe", "htmlcs"}, multi.Runners)
+
+	rerun, err := service.Rerun(multi.ID)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	assert.Nil(t, rerun)
+
+	byDefault, err := service.Create(Request{URL: "http://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"htmlcs"}, byDefault.RunnerList())
+
+	_, err = service.Create(Request{URL: "http://example.com", Runners: []string{"--debug"}})
+	assert.ErrorIs(t, err, ErrInvalidOptions)
+}
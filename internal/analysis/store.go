@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store persists analysis tasks.
+type Store interface {
+	// Create stores a new analysis task.
+	Create(analysis *Analysis) error
+	// GetByID returns an analysis task by its ID.
+	GetByID(id string) (*Analysis, bool)
+	// GetAll returns all analysis tasks.
+	GetAll() []*Analysis
+	// GetCompleted returns all completed analysis tasks.
+	GetCompleted() []*Analysis
+	// UpdateStatus updates the status of an analysis task.
+	UpdateStatus(id string, status AnalysisStatus) error
+	// UpdateResult updates the result of an analysis task.
+	UpdateResult(id string, status AnalysisStatus, result []Issue, errorMessage string) error
+	// UpdateSize updates the fetched size of the target URL in bytes.
+	UpdateSize(id string, size int64) error
+	// Delete removes an analysis task.
+	Delete(id string) error
+}
+
+// RecordStore persists the opaque records of other services, such as webhooks, by collection
+// and key.
+type RecordStore interface {
+	// PutRecord creates or replaces a record.
+	PutRecord(collection, key string, value []byte) error
+	// DeleteRecord removes a record, if it exists.
+	DeleteRecord(collection, key string) error
+	// Records returns every record of a collection keyed by key.
+	Records(collection string) map[string][]byte
+}
+
+// SecretSealer is implemented by stores that write analyses to disk. Until SealSecrets is called
+// they keep the inline credentials of analyses in memory only, so those are lost on restart;
+// afterwards they persist them encrypted with key.
+type SecretSealer interface {
+	SealSecrets(key string) error
+}
+
+// MemoryStore is a Store and ProfileStore that keeps analysis tasks and profiles in memory.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	analyses map[string]*Analysis
+	profiles map[string][]byte
+}
+
+// NewMemoryStore creates a new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		analyses: make(map[string]*Analysis),
+		profiles: make(map[string][]byte),
+	}
+}
+
+// Create stores a new analysis task.
+func (s *MemoryStore) Create(analysis *Analysis) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.analyses[analysis.ID]; ok {
+		return fmt.Errorf("analysis %s already exists", analysis.ID)
+	}
+	s.analyses[analysis.ID] = analysis
+	return nil
+}
+
+// GetByID returns an analysis task by its ID.
+func (s *MemoryStore) GetByID(id string) (*Analysis, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	analysis, ok := s.analyses[id]
+	return analysis, ok
+}
+
+// GetAll returns all analysis tasks.
+func (s *MemoryStore) GetAll() []*Analysis {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	analyses := make([]*Analysis, 0, len(s.analyses))
+	for _, analysis := range s.analyses {
+		analyses = append(analyses, analysis)
+	}
+	return analyses
+}
+
+// GetCompleted returns all completed analysis tasks.
+func (s *MemoryStore) GetCompleted() []*Analysis {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	analyses := make([]*Analysis, 0, len(s.analyses))
+	for _, analysis := range s.analyses {
+		if analysis.Status == StatusCompleted {
+			analyses = append(analyses, analysis)
+		}
+	}
+	return analyses
+}
+
+// UpdateStatus updates the status of an analysis task.
+func (s *MemoryStore) UpdateStatus(id string, status AnalysisStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, ok := s.analyses[id]
+	if !ok {
+		return fmt.Errorf("analysis %s not found", id)
+	}
+	applyStatus(analysis, status, time.Now())
+	return nil
+}
+
+// UpdateResult updates the result of an analysis task.
+func (s *MemoryStore) UpdateResult(id string, status AnalysisStatus, result []Issue, errorMessage string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, ok := s.analyses[id]
+	if !ok {
+		return fmt.Errorf("analysis %s not found", id)
+	}
+	applyResult(analysis, status, result, errorMessage, time.Now())
+	return nil
+}
+
+// UpdateSize updates the fetched size of the target URL in bytes.
+func (s *MemoryStore) UpdateSize(id string, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, ok := s.analyses[id]
+	if !ok {
+		return fmt.Errorf("analysis %s not found", id)
+	}
+	analysis.SizeBytes = size
+	analysis.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete removes an analysis task.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.analyses[id]; !ok {
+		return fmt.Errorf("analysis %s not found", id)
+	}
+	delete(s.analyses, id)
+	return nil
+}
+
+// CreateProfile stores a new sealed profile.
+func (s *MemoryStore) CreateProfile(name string, sealed []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.profiles[name]; ok {
+		return ErrProfileExists
+	}
+	s.profiles[name] = sealed
+	return nil
+}
+
+// GetProfile returns a sealed profile by its name.
+func (s *MemoryStore) GetProfile(name string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sealed, ok := s.profiles[name]
+	return sealed, ok
+}
+
+// ListProfiles returns every sealed profile keyed by name.
+func (s *MemoryStore) ListProfiles() map[string][]byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profiles := make(map[string][]byte, len(s.profiles))
+	for name, sealed := range s.profiles {
+		profiles[name] = sealed
+	}
+	return profiles
+}
+
+// DeleteProfile removes a profile.
+func (s *MemoryStore) DeleteProfile(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.profiles[name]; !ok {
+		return ErrProfileNotFound
+	}
+	delete(s.profiles, name)
+	return nil
+}
+
+// applyStatus moves an analysis to the given status, stamping the transition-specific timestamps.
+func applyStatus(analysis *Analysis, status AnalysisStatus, now time.Time) {
+	if status == StatusPending {
+		// Back in the queue: the next run starts from scratch.
+		analysis.StartedAt = time.Time{}
+	}
+	if status == StatusProcessing {
+		if analysis.StartedAt.IsZero() {
+			analysis.StartedAt = now
+		}
+	}
+	if status == StatusCompleted || status == StatusFailed || status == StatusCancelled {
+		if analysis.CompletedAt.IsZero() {
+			analysis.CompletedAt = now
+		}
+		// Compute duration from StartedAt if available, otherwise from CreatedAt
+		start := analysis.StartedAt
+		if start.IsZero() {
+			start = analysis.CreatedAt
+		}
+		dur := analysis.CompletedAt.Sub(start)
+		if dur < 0 {
+			dur = 0
+		}
+		analysis.DurationMs = dur.Milliseconds()
+	}
+
+	analysis.Status = status
+	analysis.UpdatedAt = now
+}
+
+// applyResult stores the outcome of an analysis along with its final status and, if the
+// analysis has a quality gate, its verdict.
+func applyResult(analysis *Analysis, status AnalysisStatus, result []Issue, errorMessage string, now time.Time) {
+	analysis.Result = result
+	analysis.ErrorMessage = errorMessage
+	analysis.Gate, analysis.GateReasons = "", nil
+	if analysis.QualityGate != nil && status != StatusPending && status != StatusProcessing {
+		analysis.Gate, analysis.GateReasons = analysis.QualityGate.Evaluate(status, result)
+	}
+	applyStatus(analysis, status, now)
+}
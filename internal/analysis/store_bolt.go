@@ -0,0 +1,387 @@
+package analysis
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	analysesBucket = []byte("analyses")
+	profilesBucket = []byte("profiles")
+)
+
+// recordsBucketPrefix prefixes the buckets of RecordStore collections.
+const recordsBucketPrefix = "records/"
+
+// BoltStore is a Store, ProfileStore, RecordStore and SecretSealer that persists analysis tasks and profiles
+// in an embedded bbolt database. The inline credentials of analyses are never written in
+// plaintext: they are redacted on disk and kept in memory, or sealed once SealSecrets is called.
+type BoltStore struct {
+	db *bolt.DB
+
+	mu sync.Mutex
+	// credentials holds the inline credentials of analyses while they are not sealed.
+	credentials map[string]*Auth
+	// aead seals inline credentials, or is nil until SealSecrets is called.
+	aead cipher.AEAD
+}
+
+// boltAnalysis is the record of an analysis on disk: its credentials are redacted, with their
+// sealed form alongside when the store has a key.
+type boltAnalysis struct {
+	Analysis
+	SealedAuth []byte `json:"sealedAuth,omitempty"`
+}
+
+// NewBoltStore opens (or creates) the bbolt database at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{analysesBucket, profilesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize bolt store: %w", err)
+	}
+
+	return &BoltStore{db: db, credentials: make(map[string]*Auth)}, nil
+}
+
+// SealSecrets makes the store persist the inline credentials of analyses encrypted with key, so
+// that they survive a restart.
+func (s *BoltStore) SealSecrets(key string) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return fmt.Errorf("failed to create credentials cipher: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aead = aead
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Create stores a new analysis task.
+func (s *BoltStore) Create(analysis *Analysis) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(analysesBucket)
+		if b.Get([]byte(analysis.ID)) != nil {
+			return fmt.Errorf("analysis %s already exists", analysis.ID)
+		}
+		return s.put(b, analysis)
+	})
+}
+
+// GetByID returns an analysis task by its ID.
+func (s *BoltStore) GetByID(id string) (*Analysis, bool) {
+	var analysis *Analysis
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(analysesBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		a, err := s.decode(data)
+		if err != nil {
+			return err
+		}
+		analysis = a
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading analysis %s: %v\n", id, err)
+		return nil, false
+	}
+	return analysis, analysis != nil
+}
+
+// GetAll returns all analysis tasks.
+func (s *BoltStore) GetAll() []*Analysis {
+	return s.list(func(*Analysis) bool { return true })
+}
+
+// GetCompleted returns all completed analysis tasks.
+func (s *BoltStore) GetCompleted() []*Analysis {
+	return s.list(func(a *Analysis) bool { return a.Status == StatusCompleted })
+}
+
+// UpdateStatus updates the status of an analysis task.
+func (s *BoltStore) UpdateStatus(id string, status AnalysisStatus) error {
+	return s.update(id, func(a *Analysis) {
+		applyStatus(a, status, time.Now())
+	})
+}
+
+// UpdateResult updates the result of an analysis task.
+func (s *BoltStore) UpdateResult(id string, status AnalysisStatus, result []Issue, errorMessage string) error {
+	return s.update(id, func(a *Analysis) {
+		applyResult(a, status, result, errorMessage, time.Now())
+	})
+}
+
+// UpdateSize updates the fetched size of the target URL in bytes.
+func (s *BoltStore) UpdateSize(id string, size int64) error {
+	return s.update(id, func(a *Analysis) {
+		a.SizeBytes = size
+		a.UpdatedAt = time.Now()
+	})
+}
+
+// Delete removes an analysis task.
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(analysesBucket)
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("analysis %s not found", id)
+		}
+		if err := b.Delete([]byte(id)); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		delete(s.credentials, id)
+		s.mu.Unlock()
+		return nil
+	})
+}
+
+// CreateProfile stores a new sealed profile.
+func (s *BoltStore) CreateProfile(name string, sealed []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(profilesBucket)
+		if b.Get([]byte(name)) != nil {
+			return ErrProfileExists
+		}
+		return b.Put([]byte(name), sealed)
+	})
+}
+
+// GetProfile returns a sealed profile by its name.
+func (s *BoltStore) GetProfile(name string) ([]byte, bool) {
+	var sealed []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket(profilesBucket).Get([]byte(name)); data != nil {
+			// Values are only valid during the transaction.
+			sealed = append([]byte{}, data...)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading profile %s: %v\n", name, err)
+		return nil, false
+	}
+	return sealed, sealed != nil
+}
+
+// ListProfiles returns every sealed profile keyed by name.
+func (s *BoltStore) ListProfiles() map[string][]byte {
+	profiles := make(map[string][]byte)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(profilesBucket).ForEach(func(k, v []byte) error {
+			profiles[string(k)] = append([]byte{}, v...)
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing profiles: %v\n", err)
+	}
+	return profiles
+}
+
+// DeleteProfile removes a profile.
+func (s *BoltStore) DeleteProfile(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(profilesBucket)
+		if b.Get([]byte(name)) == nil {
+			return ErrProfileNotFound
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+// PutRecord creates or replaces a record.
+func (s *BoltStore) PutRecord(collection, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(recordsBucketPrefix + collection))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+}
+
+// DeleteRecord removes a record, if it exists.
+func (s *BoltStore) DeleteRecord(collection, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(recordsBucketPrefix + collection))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// Records returns every record of a collection keyed by key.
+func (s *BoltStore) Records(collection string) map[string][]byte {
+	records := make(map[string][]byte)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(recordsBucketPrefix + collection))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			records[string(k)] = append([]byte{}, v...)
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Error listing %s records: %v", collection, err)
+	}
+	return records
+}
+
+// list returns the analyses accepted by keep, skipping records that cannot be decoded.
+func (s *BoltStore) list(keep func(*Analysis) bool) []*Analysis {
+	analyses := []*Analysis{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(analysesBucket).ForEach(func(k, v []byte) error {
+			a, err := s.decode(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error decoding analysis %s: %v\n", k, err)
+				return nil
+			}
+			if keep(a) {
+				analyses = append(analyses, a)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing analyses: %v\n", err)
+	}
+	return analyses
+}
+
+// update loads an analysis, applies fn and writes it back in a single transaction.
+func (s *BoltStore) update(id string, fn func(*Analysis)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(analysesBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("analysis %s not found", id)
+		}
+		a, err := s.decode(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode analysis %s: %w", id, err)
+		}
+		fn(a)
+		return s.put(b, a)
+	})
+}
+
+// put writes an analysis with its credentials redacted, sealing them alongside when the store has
+// a key and keeping them in memory otherwise.
+func (s *BoltStore) put(b *bolt.Bucket, analysis *Analysis) error {
+	record := boltAnalysis{Analysis: *analysis}
+	if analysis.Auth != nil {
+		record.Auth = analysis.Auth.Redacted()
+
+		s.mu.Lock()
+		aead := s.aead
+		if aead == nil {
+			s.credentials[analysis.ID] = analysis.Auth
+		}
+		s.mu.Unlock()
+
+		if aead != nil {
+			plaintext, err := json.Marshal(analysis.Auth)
+			if err != nil {
+				return fmt.Errorf("failed to encode credentials of analysis %s: %w", analysis.ID, err)
+			}
+			nonce := make([]byte, aead.NonceSize())
+			if _, err := rand.Read(nonce); err != nil {
+				return fmt.Errorf("failed to generate nonce: %w", err)
+			}
+			record.SealedAuth = aead.Seal(nonce, nonce, plaintext, []byte(analysis.ID))
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis %s: %w", analysis.ID, err)
+	}
+	return b.Put([]byte(analysis.ID), data)
+}
+
+// decode reads an analysis written by put and restores its credentials. Credentials that were
+// kept in memory before a restart, or that cannot be unsealed, stay redacted.
+func (s *BoltStore) decode(data []byte) (*Analysis, error) {
+	var record boltAnalysis
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	a := &record.Analysis
+	if a.Auth == nil {
+		return a, nil
+	}
+
+	s.mu.Lock()
+	aead := s.aead
+	auth, ok := s.credentials[a.ID]
+	s.mu.Unlock()
+	switch {
+	case ok:
+		a.Auth = auth
+	case record.SealedAuth != nil && aead != nil:
+		auth, err := openAuth(aead, a.ID, record.SealedAuth)
+		if err != nil {
+			log.Printf("Error unsealing credentials of analysis %s: %v", a.ID, err)
+			break
+		}
+		a.Auth = auth
+	}
+	return a, nil
+}
+
+// openAuth decrypts credentials sealed by put.
+func openAuth(aead cipher.AEAD, id string, sealed []byte) (*Auth, error) {
+	size := aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("sealed credentials are too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:size], sealed[size:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials (wrong key?): %w", err)
+	}
+	var auth Auth
+	if err := json.Unmarshal(plaintext, &auth); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return &auth, nil
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStoreLifecycle(t *testing.T, store Store) {
+	a := &Analysis{ID: "a1", URL: "http://example.com", Status: StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, store.Create(a))
+	assert.Error(t, store.Create(a), "duplicate IDs must be rejected")
+
+	require.NoError(t, store.UpdateStatus("a1", StatusProcessing))
+	require.NoError(t, store.UpdateSize("a1", 1234))
+	issues := []Issue{{Code: "WCAG2AA.Principle1.Guideline1_1.1_1_1.H37", Type: "error", TypeCode: 1}}
+	require.NoError(t, store.UpdateResult("a1", StatusCompleted, issues, ""))
+
+	got, ok := store.GetByID("a1")
+	require.True(t, ok)
+	assert.Equal(t, StatusCompleted, got.Status)
+	assert.Equal(t, int64(1234), got.SizeBytes)
+	assert.Equal(t, issues[0].Code, got.Result[0].Code)
+	assert.False(t, got.StartedAt.IsZero())
+	assert.False(t, got.CompletedAt.IsZero())
+
+	require.NoError(t, store.Create(&Analysis{ID: "a2", URL: "http://example.org", Status: StatusPending}))
+	assert.Len(t, store.GetAll(), 2)
+	assert.Len(t, store.GetCompleted(), 1)
+
+	_, ok = store.GetByID("missing")
+	assert.False(t, ok)
+	assert.Error(t, store.UpdateStatus("missing", StatusFailed))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStoreLifecycle(t, NewMemoryStore())
+}
+
+func TestBoltStore(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "analyses.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	testStoreLifecycle(t, store)
+}
+
+func TestRequeuePendingAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analyses.db")
+
+	store, err := NewBoltStore(path)
+	require.NoError(t, err)
+	service := NewServiceWithStore(store, 10)
+	pending, err := service.Create(Request{URL: "http://example.com/pending"})
+	require.NoError(t, err)
+	processing, err := service.Create(Request{URL: "http://example.com/processing"})
+	require.NoError(t, err)
+	done, err := service.Create(Request{URL: "http://example.com/done"})
+	require.NoError(t, err)
+	service.UpdateStatus(processing.ID, StatusProcessing)
+	service.UpdateResult(done.ID, StatusCompleted, nil, "")
+	require.NoError(t, store.Close())
+
+	store, err = NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	service = NewServiceWithStore(store, 10)
+
+	assert.Equal(t, 2, service.RequeuePending())
+	requeued := []string{service.GetNextFromQueue(), service.GetNextFromQueue()}
+	assert.ElementsMatch(t, []string{pending.ID, processing.ID}, requeued)
+
+	got, ok := service.GetByID(processing.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusPending, got.Status)
+	assert.True(t, got.StartedAt.IsZero())
+}
+
+func TestBoltStoreKeepsCredentialsOffDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analyses.db")
+	auth := &Auth{
+		Headers:   map[string]string{"Authorization": "Bearer secret-token"},
+		Cookies:   []Cookie{{Name: "session", Value: "secret-cookie"}},
+		BasicAuth: &BasicAuth{Username: "admin", Password: "secret-password"},
+		Actions:   []string{"set field #password to secret-field"},
+	}
+
+	for name, key := range map[string]string{"in memory": "", "sealed": "profiles-key"} {
+		store, err := NewBoltStore(path)
+		require.NoError(t, err, name)
+		if key != "" {
+			require.NoError(t, store.SealSecrets(key), name)
+		}
+		require.NoError(t, store.Create(&Analysis{ID: name, URL: "http://example.com", Status: StatusPending, Auth: auth}), name)
+		require.NoError(t, store.UpdateStatus(name, StatusProcessing), name)
+
+		got, ok := store.GetByID(name)
+		require.True(t, ok, name)
+		assert.Equal(t, auth, got.Auth, "credentials are usable until the restart (%s)", name)
+		require.NoError(t, store.Close(), name)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, name)
+		assert.NotContains(t, string(data), "secret", "credentials are not written in plaintext (%s)", name)
+
+		store, err = NewBoltStore(path)
+		require.NoError(t, err, name)
+		if key != "" {
+			require.NoError(t, store.SealSecrets(key), name)
+		}
+		got, ok = store.GetByID(name)
+		require.True(t, ok, name)
+		if key != "" {
+			assert.Equal(t, auth, got.Auth, "sealed credentials survive a restart")
+		} else {
+			assert.Equal(t, auth.Redacted(), got.Auth, "unsealed credentials are lost on restart")
+			assert.True(t, got.Auth.isRedacted())
+		}
+		require.NoError(t, store.Close(), name)
+	}
+}
+
+func TestExecuteRefusesRedactedCredentials(t *testing.T) {
+	service := NewService(10)
+	a := &Analysis{URL: "http://example.com", Auth: (&Auth{Headers: map[string]string{"X-Token": "secret"}}).Redacted()}
+
+	_, err := service.execute(context.Background(), a, nil)
+	assert.ErrorContains(t, err, "submit the analysis again")
+}
@@ -0,0 +1,292 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+)
+
+// htmlcsCriterionPattern extracts the success criterion from HTML_CodeSniffer codes such as
+// "WCAG2AA.Principle1.Guideline1_1.1_1_1.H37".
+var htmlcsCriterionPattern = regexp.MustCompile(`Guideline\d+_\d+\.(\d+)_(\d+)_(\d+)`)
+
+// htmlcsCriteria lists the success criteria for which HTML_CodeSniffer reports errors. Its codes
+// name their criterion, which htmlcsCriterionPattern extracts; its other sniffs only raise
+// warnings and notices for a manual review.
+var htmlcsCriteria = []string{
+	"1.1.1", // H30, H36, H37 and other text alternative checks
+	"1.3.1", // H39, H42, H43, H44, H63, H71 and other structure checks
+	"1.4.3", // G18 and G145 contrast checks
+	"2.2.1", // F41 meta refresh
+	"2.2.2", // F4 blink and F47 marquee
+	"2.4.1", // H64 frame titles
+	"2.4.2", // H25 page title
+	"3.1.1", // H57 page language
+	"3.2.2", // H32 forms without a submit button
+	"4.1.1", // F77 duplicate IDs
+	"4.1.2", // H91 names, roles and values of controls
+}
+
+// axeCriteria maps axe rule IDs to the WCAG success criterion they test. Where axe tags a rule
+// with several criteria, the one HTML_CodeSniffer reports for the same problem is used so that
+// findings of both runners line up.
+var axeCriteria = map[string]string{
+	"area-alt":                    "1.1.1",
+	"aria-allowed-attr":           "4.1.2",
+	"aria-command-name":           "4.1.2",
+	"aria-hidden-body":            "4.1.2",
+	"aria-hidden-focus":           "4.1.2",
+	"aria-input-field-name":       "4.1.2",
+	"aria-meter-name":             "1.1.1",
+	"aria-progressbar-name":       "1.1.1",
+	"aria-required-attr":          "4.1.2",
+	"aria-required-children":      "1.3.1",
+	"aria-required-parent":        "1.3.1",
+	"aria-roles":                  "4.1.2",
+	"aria-toggle-field-name":      "4.1.2",
+	"aria-tooltip-name":           "4.1.2",
+	"aria-valid-attr":             "4.1.2",
+	"aria-valid-attr-value":       "4.1.2",
+	"audio-caption":               "1.2.1",
+	"autocomplete-valid":          "1.3.5",
+	"avoid-inline-spacing":        "1.4.12",
+	"blink":                       "2.2.2",
+	"button-name":                 "4.1.2",
+	"bypass":                      "2.4.1",
+	"color-contrast":              "1.4.3",
+	"color-contrast-enhanced":     "1.4.6",
+	"definition-list":             "1.3.1",
+	"dlitem":                      "1.3.1",
+	"document-title":              "2.4.2",
+	"duplicate-id":                "4.1.1",
+	"duplicate-id-active":         "4.1.1",
+	"duplicate-id-aria":           "4.1.1",
+	"form-field-multiple-labels":  "3.3.2",
+	"frame-focusable-content":     "2.1.1",
+	"frame-title":                 "4.1.2",
+	"html-has-lang":               "3.1.1",
+	"html-lang-valid":             "3.1.1",
+	"html-xml-lang-mismatch":      "3.1.1",
+	"image-alt":                   "1.1.1",
+	"input-button-name":           "4.1.2",
+	"input-image-alt":             "1.1.1",
+	"label":                       "4.1.2",
+	"link-in-text-block":          "1.4.1",
+	"link-name":                   "4.1.2",
+	"list":                        "1.3.1",
+	"listitem":                    "1.3.1",
+	"marquee":                     "2.2.2",
+	"meta-refresh":                "2.2.1",
+	"meta-viewport":               "1.4.4",
+	"nested-interactive":          "4.1.2",
+	"no-autoplay-audio":           "1.4.2",
+	"object-alt":                  "1.1.1",
+	"role-img-alt":                "1.1.1",
+	"scrollable-region-focusable": "2.1.1",
+	"select-name":                 "4.1.2",
+	"server-side-image-map":       "2.1.1",
+	"svg-img-alt":                 "1.1.1",
+	"target-size":                 "2.5.8",
+	"td-headers-attr":             "1.3.1",
+	"th-has-data-cells":           "1.3.1",
+	"valid-lang":                  "3.1.2",
+	"video-caption":               "1.2.2",
+}
+
+// axeTagPattern extracts the success criterion from axe tags such as "wcag143" or "wcag1412".
+var axeTagPattern = regexp.MustCompile(`^wcag(\d)(\d)(\d+)$`)
+
+// WCAGCriterion returns the WCAG success criterion (e.g. "1.1.1") an issue relates to,
+// or an empty string if it cannot be determined. Besides HTML_CodeSniffer codes and known axe
+// rules, it reads the WCAG tags axe may report in the runner extras of an issue.
+func WCAGCriterion(issue Issue) string {
+	if m := htmlcsCriterionPattern.FindStringSubmatch(issue.Code); m != nil {
+		return strings.Join(m[1:], ".")
+	}
+	if criterion, ok := axeCriteria[issue.Code]; ok {
+		return criterion
+	}
+	tags, _ := issue.RunnerExtras["tags"].([]interface{})
+	for _, tag := range tags {
+		s, _ := tag.(string)
+		if m := axeTagPattern.FindStringSubmatch(s); m != nil {
+			if criterion := strings.Join(m[1:], "."); criteriaByNumber[criterion].Number != "" {
+				return criterion
+			}
+		}
+	}
+	return ""
+}
+
+// Criterion describes a WCAG success criterion.
+type Criterion struct {
+	// Number is the success criterion number, e.g. "1.4.3".
+	Number string `json:"number"`
+	Name   string `json:"name"`
+	// Level is the conformance level: "A", "AA" or "AAA".
+	Level string `json:"level"`
+	// Version is the WCAG version that introduced the criterion.
+	Version string `json:"version"`
+	// Removed is the WCAG version that made the criterion obsolete, if any.
+	Removed string `json:"removed,omitempty"`
+
+	slug string
+}
+
+// URL returns the link to the W3C "Understanding" document of the criterion.
+func (c Criterion) URL() string {
+	return "https://www.w3.org/WAI/WCAG22/Understanding/" + c.slug + ".html"
+}
+
+// Principle returns the name of the WCAG principle the criterion belongs to.
+func (c Criterion) Principle() string {
+	return principles[c.Number[:1]]
+}
+
+// principles names the four WCAG principles by number.
+var principles = map[string]string{
+	"1": "Perceivable",
+	"2": "Operable",
+	"3": "Understandable",
+	"4": "Robust",
+}
+
+// Criteria lists the WCAG 2.x success criteria in document order.
+var Criteria = []Criterion{
+	{Number: "1.1.1", Name: "Non-text Content", Level: "A", Version: "2.0", slug: "non-text-content"},
+	{Number: "1.2.1", Name: "Audio-only and Video-only (Prerecorded)", Level: "A", Version: "2.0", slug: "audio-only-and-video-only-prerecorded"},
+	{Number: "1.2.2", Name: "Captions (Prerecorded)", Level: "A", Version: "2.0", slug: "captions-prerecorded"},
+	{Number: "1.2.3", Name: "Audio Description or Media Alternative (Prerecorded)", Level: "A", Version: "2.0", slug: "audio-description-or-media-alternative-prerecorded"},
+	{Number: "1.2.4", Name: "Captions (Live)", Level: "AA", Version: "2.0", slug: "captions-live"},
+	{Number: "1.2.5", Name: "Audio Description (Prerecorded)", Level: "AA", Version: "2.0", slug: "audio-description-prerecorded"},
+	{Number: "1.2.6", Name: "Sign Language (Prerecorded)", Level: "AAA", Version: "2.0", slug: "sign-language-prerecorded"},
+	{Number: "1.2.7", Name: "Extended Audio Description (Prerecorded)", Level: "AAA", Version: "2.0", slug: "extended-audio-description-prerecorded"},
+	{Number: "1.2.8", Name: "Media Alternative (Prerecorded)", Level: "AAA", Version: "2.0", slug: "media-alternative-prerecorded"},
+	{Number: "1.2.9", Name: "Audio-only (Live)", Level: "AAA", Version: "2.0", slug: "audio-only-live"},
+	{Number: "1.3.1", Name: "Info and Relationships", Level: "A", Version: "2.0", slug: "info-and-relationships"},
+	{Number: "1.3.2", Name: "Meaningful Sequence", Level: "A", Version: "2.0", slug: "meaningful-sequence"},
+	{Number: "1.3.3", Name: "Sensory Characteristics", Level: "A", Version: "2.0", slug: "sensory-characteristics"},
+	{Number: "1.3.4", Name: "Orientation", Level: "AA", Version: "2.1", slug: "orientation"},
+	{Number: "1.3.5", Name: "Identify Input Purpose", Level: "AA", Version: "2.1", slug: "identify-input-purpose"},
+	{Number: "1.3.6", Name: "Identify Purpose", Level: "AAA", Version: "2.1", slug: "identify-purpose"},
+	{Number: "1.4.1", Name: "Use of Color", Level: "A", Version: "2.0", slug: "use-of-color"},
+	{Number: "1.4.2", Name: "Audio Control", Level: "A", Version: "2.0", slug: "audio-control"},
+	{Number: "1.4.3", Name: "Contrast (Minimum)", Level: "AA", Version: "2.0", slug: "contrast-minimum"},
+	{Number: "1.4.4", Name: "Resize Text", Level: "AA", Version: "2.0", slug: "resize-text"},
+	{Number: "1.4.5", Name: "Images of Text", Level: "AA", Version: "2.0", slug: "images-of-text"},
+	{Number: "1.4.6", Name: "Contrast (Enhanced)", Level: "AAA", Version: "2.0", slug: "contrast-enhanced"},
+	{Number: "1.4.7", Name: "Low or No Background Audio", Level: "AAA", Version: "2.0", slug: "low-or-no-background-audio"},
+	{Number: "1.4.8", Name: "Visual Presentation", Level: "AAA", Version: "2.0", slug: "visual-presentation"},
+	{Number: "1.4.9", Name: "Images of Text (No Exception)", Level: "AAA", Version: "2.0", slug: "images-of-text-no-exception"},
+	{Number: "1.4.10", Name: "Reflow", Level: "AA", Version: "2.1", slug: "reflow"},
+	{Number: "1.4.11", Name: "Non-text Contrast", Level: "AA", Version: "2.1", slug: "non-text-contrast"},
+	{Number: "1.4.12", Name: "Text Spacing", Level: "AA", Version: "2.1", slug: "text-spacing"},
+	{Number: "1.4.13", Name: "Content on Hover or Focus", Level: "AA", Version: "2.1", slug: "content-on-hover-or-focus"},
+	{Number: "2.1.1", Name: "Keyboard", Level: "A", Version: "2.0", slug: "keyboard"},
+	{Number: "2.1.2", Name: "No Keyboard Trap", Level: "A", Version: "2.0", slug: "no-keyboard-trap"},
+	{Number: "2.1.3", Name: "Keyboard (No Exception)", Level: "AAA", Version: "2.0", slug: "keyboard-no-exception"},
+	{Number: "2.1.4", Name: "Character Key Shortcuts", Level: "A", Version: "2.1", slug: "character-key-shortcuts"},
+	{Number: "2.2.1", Name: "Timing Adjustable", Level: "A", Version: "2.0", slug: "timing-adjustable"},
+	{Number: "2.2.2", Name: "Pause, Stop, Hide", Level: "A", Version: "2.0", slug: "pause-stop-hide"},
+	{Number: "2.2.3", Name: "No Timing", Level: "AAA", Version: "2.0", slug: "no-timing"},
+	{Number: "2.2.4", Name: "Interruptions", Level: "AAA", Version: "2.0", slug: "interruptions"},
+	{Number: "2.2.5", Name: "Re-authenticating", Level: "AAA", Version: "2.0", slug: "re-authenticating"},
+	{Number: "2.2.6", Name: "Timeouts", Level: "AAA", Version: "2.1", slug: "timeouts"},
+	{Number: "2.3.1", Name: "Three Flashes or Below Threshold", Level: "A", Version: "2.0", slug: "three-flashes-or-below-threshold"},
+	{Number: "2.3.2", Name: "Three Flashes", Level: "AAA", Version: "2.0", slug: "three-flashes"},
+	{Number: "2.3.3", Name: "Animation from Interactions", Level: "AAA", Version: "2.1", slug: "animation-from-interactions"},
+	{Number: "2.4.1", Name: "Bypass Blocks", Level: "A", Version: "2.0", slug: "bypass-blocks"},
+	{Number: "2.4.2", Name: "Page Titled", Level: "A", Version: "2.0", slug: "page-titled"},
+	{Number: "2.4.3", Name: "Focus Order", Level: "A", Version: "2.0", slug: "focus-order"},
+	{Number: "2.4.4", Name: "Link Purpose (In Context)", Level: "A", Version: "2.0", slug: "link-purpose-in-context"},
+	{Number: "2.4.5", Name: "Multiple Ways", Level: "AA", Version: "2.0", slug: "multiple-ways"},
+	{Number: "2.4.6", Name: "Headings and Labels", Level: "AA", Version: "2.0", slug: "headings-and-labels"},
+	{Number: "2.4.7", Name: "Focus Visible", Level: "AA", Version: "2.0", slug: "focus-visible"},
+	{Number: "2.4.8", Name: "Location", Level: "AAA", Version: "2.0", slug: "location"},
+	{Number: "2.4.9", Name: "Link Purpose (Link Only)", Level: "AAA", Version: "2.0", slug: "link-purpose-link-only"},
+	{Number: "2.4.10", Name: "Section Headings", Level: "AAA", Version: "2.0", slug: "section-headings"},
+	{Number: "2.4.11", Name: "Focus Not Obscured (Minimum)", Level: "AA", Version: "2.2", slug: "focus-not-obscured-minimum"},
+	{Number: "2.4.12", Name: "Focus Not Obscured (Enhanced)", Level: "AAA", Version: "2.2", slug: "focus-not-obscured-enhanced"},
+	{Number: "2.4.13", Name: "Focus Appearance", Level: "AAA", Version: "2.2", slug: "focus-appearance"},
+	{Number: "2.5.1", Name: "Pointer Gestures", Level: "A", Version: "2.1", slug: "pointer-gestures"},
+	{Number: "2.5.2", Name: "Pointer Cancellation", Level: "A", Version: "2.1", slug: "pointer-cancellation"},
+	{Number: "2.5.3", Name: "Label in Name", Level: "A", Version: "2.1", slug: "label-in-name"},
+	{Number: "2.5.4", Name: "Motion Actuation", Level: "A", Version: "2.1", slug: "motion-actuation"},
+	{Number: "2.5.5", Name: "Target Size (Enhanced)", Level: "AAA", Version: "2.1", slug: "target-size-enhanced"},
+	{Number: "2.5.6", Name: "Concurrent Input Mechanisms", Level: "AAA", Version: "2.1", slug: "concurrent-input-mechanisms"},
+	{Number: "2.5.7", Name: "Dragging Movements", Level: "AA", Version: "2.2", slug: "dragging-movements"},
+	{Number: "2.5.8", Name: "Target Size (Minimum)", Level: "AA", Version: "2.2", slug: "target-size-minimum"},
+	{Number: "3.1.1", Name: "Language of Page", Level: "A", Version: "2.0", slug: "language-of-page"},
+	{Number: "3.1.2", Name: "Language of Parts", Level: "AA", Version: "2.0", slug: "language-of-parts"},
+	{Number: "3.1.3", Name: "Unusual Words", Level: "AAA", Version: "2.0", slug: "unusual-words"},
+	{Number: "3.1.4", Name: "Abbreviations", Level: "AAA", Version: "2.0", slug: "abbreviations"},
+	{Number: "3.1.5", Name: "Reading Level", Level: "AAA", Version: "2.0", slug: "reading-level"},
+	{Number: "3.1.6", Name: "Pronunciation", Level: "AAA", Version: "2.0", slug: "pronunciation"},
+	{Number: "3.2.1", Name: "On Focus", Level: "A", Version: "2.0", slug: "on-focus"},
+	{Number: "3.2.2", Name: "On Input", Level: "A", Version: "2.0", slug: "on-input"},
+	{Number: "3.2.3", Name: "Consistent Navigation", Level: "AA", Version: "2.0", slug: "consistent-navigation"},
+	{Number: "3.2.4", Name: "Consistent Identification", Level: "AA", Version: "2.0", slug: "consistent-identification"},
+	{Number: "3.2.5", Name: "Change on Request", Level: "AAA", Version: "2.0", slug: "change-on-request"},
+	{Number: "3.2.6", Name: "Consistent Help", Level: "A", Version: "2.2", slug: "consistent-help"},
+	{Number: "3.3.1", Name: "Error Identification", Level: "A", Version: "2.0", slug: "error-identification"},
+	{Number: "3.3.2", Name: "Labels or Instructions", Level: "A", Version: "2.0", slug: "labels-or-instructions"},
+	{Number: "3.3.3", Name: "Error Suggestion", Level: "AA", Version: "2.0", slug: "error-suggestion"},
+	{Number: "3.3.4", Name: "Error Prevention (Legal, Financial, Data)", Level: "AA", Version: "2.0", slug: "error-prevention-legal-financial-data"},
+	{Number: "3.3.5", Name: "Help", Level: "AAA", Version: "2.0", slug: "help"},
+	{Number: "3.3.6", Name: "Error Prevention (All)", Level: "AAA", Version: "2.0", slug: "error-prevention-all"},
+	{Number: "3.3.7", Name: "Redundant Entry", Level: "A", Version: "2.2", slug: "redundant-entry"},
+	{Number: "3.3.8", Name: "Accessible Authentication (Minimum)", Level: "AA", Version: "2.2", slug: "accessible-authentication-minimum"},
+	{Number: "3.3.9", Name: "Accessible Authentication (Enhanced)", Level: "AAA", Version: "2.2", slug: "accessible-authentication-enhanced"},
+	{Number: "4.1.1", Name: "Parsing", Level: "A", Version: "2.0", Removed: "2.2", slug: "parsing"},
+	{Number: "4.1.2", Name: "Name, Role, Value", Level: "A", Version: "2.0", slug: "name-role-value"},
+	{Number: "4.1.3", Name: "Status Messages", Level: "AA", Version: "2.1", slug: "status-messages"},
+}
+
+// criteriaByNumber indexes Criteria by success criterion number.
+var criteriaByNumber = func() map[string]Criterion {
+	m := make(map[string]Criterion, len(Criteria))
+	for _, c := range Criteria {
+		m[c.Number] = c
+	}
+	return m
+}()
+
+// LookupCriterion returns the success criterion with the given number, e.g. "1.4.3".
+func LookupCriterion(number string) (Criterion, bool) {
+	c, ok := criteriaByNumber[number]
+	return c, ok
+}
+
+// CriterionGroup holds the issues related to one success criterion.
+type CriterionGroup struct {
+	// Criterion is the zero Criterion for issues whose success criterion is unknown.
+	Criterion Criterion   `json:"criterion"`
+	Counts    IssueCounts `json:"counts"`
+	Issues    []Issue     `json:"issues"`
+}
+
+// GroupByCriterion groups issues by WCAG success criterion, in the document order of the
+// criteria, which sorts them by principle, guideline and criterion. Issues whose criterion is
+// unknown, or not a WCAG 2.2 criterion, come last in a group with a zero Criterion.
+func GroupByCriterion(issues []Issue) []CriterionGroup {
+	byNumber := make(map[string][]Issue)
+	var unknown []Issue
+	for _, issue := range issues {
+		number := WCAGCriterion(issue)
+		if _, ok := criteriaByNumber[number]; ok {
+			byNumber[number] = append(byNumber[number], issue)
+		} else {
+			unknown = append(unknown, issue)
+		}
+	}
+
+	groups := make([]CriterionGroup, 0, len(byNumber)+1)
+	for _, criterion := range Criteria {
+		if issues, ok := byNumber[criterion.Number]; ok {
+			groups = append(groups, CriterionGroup{Criterion: criterion, Counts: CountIssues(issues), Issues: issues})
+		}
+	}
+	if len(unknown) > 0 {
+		groups = append(groups, CriterionGroup{Counts: CountIssues(unknown), Issues: unknown})
+	}
+	return groups
+}
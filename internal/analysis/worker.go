@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// WorkerStats describes the current state of a worker pool.
+type WorkerStats struct {
+	Size         int `json:"size"`
+	Busy         int `json:"busy"`
+	Idle         int `json:"idle"`
+	PerHostLimit int `json:"perHostLimit"`
+	Waiting      int `json:"waiting"`
+}
+
+// WorkerPool processes analysis tasks from the queue with a fixed number of workers.
+type WorkerPool struct {
+	service *Service
+	size    int
+	busy    atomic.Int64
+	hosts   *hostLimiter
+	// direct holds a token for each direct analysis sharing the pool's limits.
+	direct chan struct{}
+}
+
+// NewWorkerPool creates a pool of size workers. perHostLimit caps how many analyses
+// of the same host run at the same time; zero or less means no cap.
+func NewWorkerPool(service *Service, size int, perHostLimit int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{
+		service: service,
+		size:    size,
+		hosts:   newHostLimiter(perHostLimit),
+		direct:  make(chan struct{}, size),
+	}
+}
+
+// Start launches the pool's workers.
+func (p *WorkerPool) Start() {
+	for i := 0; i < p.size; i++ {
+		go p.run()
+	}
+}
+
+// Stats returns a snapshot of the pool's busy and idle workers.
+func (p *WorkerPool) Stats() WorkerStats {
+	busy := int(p.busy.Load())
+	return WorkerStats{
+		Size:         p.size,
+		Busy:         busy,
+		Idle:         max(p.size-busy, 0),
+		PerHostLimit: p.hosts.limit,
+		Waiting:      p.hosts.waitingCount(),
+	}
+}
+
+// run is a single worker's processing loop.
+func (p *WorkerPool) run() {
+	for {
+		analysisID := p.service.GetNextFromQueue()
+		analysis, ok := p.service.getWithSecrets(analysisID)
+		if !ok || analysis.Status != StatusPending {
+			// Deleted or cancelled while waiting in the queue.
+			continue
+		}
+
+		host := hostOf(analysis.URL)
+		if !p.hosts.acquire(host, analysis.ID) {
+			// Parked until another job for the same host finishes. It still takes room in the
+			// queue so that admission keeps the backlog bounded.
+			p.service.parkedChanged(1)
+			continue
+		}
+
+		p.processHost(host, analysis)
+	}
+}
+
+// processHost processes an analysis holding a slot for host, then the jobs parked for host
+// while there are any, and releases the slot.
+func (p *WorkerPool) processHost(host string, analysis *Analysis) {
+	for {
+		p.busy.Add(1)
+		p.process(analysis)
+		p.busy.Add(-1)
+
+		var ok bool
+		if analysis, ok = p.nextParked(host); !ok {
+			return
+		}
+	}
+}
+
+// acquireDirect reserves room for a direct analysis of host: one of as many direct slots as the
+// pool has workers, and a slot for host under the per-host limit. It does not wait; it returns
+// ErrBusy if either is taken. The returned function releases them.
+func (p *WorkerPool) acquireDirect(host string) (func(), error) {
+	select {
+	case p.direct <- struct{}{}:
+	default:
+		return nil, fmt.Errorf("%w: %d direct analyses are running", ErrBusy, cap(p.direct))
+	}
+	if !p.hosts.tryAcquire(host) {
+		<-p.direct
+		return nil, fmt.Errorf("%w: %d analyses of %s are running", ErrBusy, p.hosts.limit, host)
+	}
+	return func() {
+		// A queued job may have been parked behind the direct analysis; it takes over the
+		// host slot, and the direct slot until it is done so that runs stay bounded.
+		if analysis, ok := p.nextParked(host); ok {
+			go func() {
+				p.processHost(host, analysis)
+				<-p.direct
+			}()
+			return
+		}
+		<-p.direct
+	}, nil
+}
+
+// nextParked releases the worker's slot for host, taking over the next parked job for it if any.
+func (p *WorkerPool) nextParked(host string) (*Analysis, bool) {
+	for {
+		nextID, ok := p.hosts.release(host)
+		if !ok {
+			return nil, false
+		}
+		p.service.parkedChanged(-1)
+		if analysis, ok := p.service.getWithSecrets(nextID); ok && analysis.Status == StatusPending {
+			return analysis, true
+		}
+	}
+}
+
+// process runs a single analysis and records its outcome.
+func (p *WorkerPool) process(analysis *Analysis) {
+	ctx, ok := p.service.startProcessing(context.Background(), analysis.ID)
+	if !ok {
+		// Cancelled or deleted while parked.
+		return
+	}
+
+	result, err := p.service.execute(ctx, analysis, func(size int64) {
+		p.service.UpdateSize(analysis.ID, size)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error analyzing %s: %v\n", analysis.URL, err)
+		p.service.finishProcessing(analysis.ID, StatusFailed, nil, err.Error())
+		return
+	}
+
+	p.service.finishProcessing(analysis.ID, StatusCompleted, result, "")
+}
+
+// hostOf returns the lower-cased host of rawURL, or rawURL itself if it cannot be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+// hostLimiter caps the number of concurrent analyses per host. Jobs that exceed the cap
+// are parked and handed over to the worker that frees the next slot for that host.
+type hostLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	active  map[string]int
+	waiting map[string][]string
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit:   limit,
+		active:  make(map[string]int),
+		waiting: make(map[string][]string),
+	}
+}
+
+// acquire reserves a slot for host. If the host is saturated the job is parked and false is returned.
+func (l *hostLimiter) acquire(host string, id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit > 0 && l.active[host] >= l.limit {
+		l.waiting[host] = append(l.waiting[host], id)
+		return false
+	}
+	l.active[host]++
+	return true
+}
+
+// tryAcquire reserves a slot for host like acquire, but does not park anything when the host is
+// saturated.
+func (l *hostLimiter) tryAcquire(host string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit > 0 && l.active[host] >= l.limit {
+		return false
+	}
+	l.active[host]++
+	return true
+}
+
+// release frees a slot for host. If a job is parked for the host, the slot is handed over
+// to it and its ID is returned.
+func (l *hostLimiter) release(host string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if queued := l.waiting[host]; len(queued) > 0 {
+		next := queued[0]
+		if len(queued) == 1 {
+			delete(l.waiting, host)
+		} else {
+			l.waiting[host] = queued[1:]
+		}
+		return next, true
+	}
+
+	l.active[host]--
+	if l.active[host] <= 0 {
+		delete(l.active, host)
+	}
+	return "", false
+}
+
+func (l *hostLimiter) waitingCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, queued := range l.waiting {
+		count += len(queued)
+	}
+	return count
+}
@@ -0,0 +1,33 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter(1)
+
+	assert.True(t, l.acquire("example.com", "a"))
+	assert.True(t, l.acquire("example.org", "b"), "other hosts are not affected")
+	assert.False(t, l.acquire("example.com", "c"), "saturated host parks the job")
+	assert.Equal(t, 1, l.waitingCount())
+
+	// Releasing hands the slot over to the parked job.
+	next, ok := l.release("example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "c", next)
+	assert.Equal(t, 0, l.waitingCount())
+
+	_, ok = l.release("example.com")
+	assert.False(t, ok)
+	assert.True(t, l.acquire("example.com", "d"))
+}
+
+func TestHostLimiterUnlimited(t *testing.T) {
+	l := newHostLimiter(0)
+	for _, id := range []string{"a", "b", "c"} {
+		assert.True(t, l.acquire("example.com", id))
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"pa11y-go-wrapper/internal/analysis"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment to keep the
+// connection open through proxies.
+const sseHeartbeatInterval = 15 * time.Second
+
+// GetQueueItemEvents streams the events of a single analysis as Server-Sent Events. The
+// current state is sent first, and the stream ends once the analysis is finished or deleted.
+func (h *Handlers) GetQueueItemEvents(c *gin.Context) {
+	id := c.Param("id")
+	sub := h.analysisService.Events().Subscribe(func(e analysis.Event) bool { return e.ID == id })
+	defer sub.Close()
+
+	// Subscribe before reading the current state so no transition is missed in between.
+	a, ok := h.analysisService.GetByID(id)
+	if !ok {
+		respondAnalysisError(c, analysis.ErrNotFound)
+		return
+	}
+	current := analysis.Event{
+		Type:         analysis.EventStatus,
+		ID:           a.ID,
+		URL:          a.URL,
+		Status:       a.Status,
+		SizeBytes:    a.SizeBytes,
+		ErrorMessage: a.ErrorMessage,
+		Time:         a.UpdatedAt,
+	}
+	if a.Status == analysis.StatusCompleted {
+		counts := analysis.CountIssues(a.Result)
+		current.Issues = &counts
+	}
+
+	setSSEHeaders(c)
+	c.SSEvent(string(current.Type), current)
+	c.Writer.Flush()
+	if current.Finished() {
+		return
+	}
+	streamEvents(c, sub, true)
+}
+
+// GetEvents streams the events of every analysis as Server-Sent Events until the client
+// disconnects.
+func (h *Handlers) GetEvents(c *gin.Context) {
+	sub := h.analysisService.Events().Subscribe(nil)
+	defer sub.Close()
+
+	setSSEHeaders(c)
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+	streamEvents(c, sub, false)
+}
+
+func setSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop reverse proxies such as nginx from buffering the stream.
+	c.Header("X-Accel-Buffering", "no")
+}
+
+// streamEvents writes the events of sub to the client until it disconnects, or until an
+// analysis finishes when stopWhenFinished is set.
+func streamEvents(c *gin.Context, sub *analysis.Subscription, stopWhenFinished bool) {
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(e.Type), e)
+			return !(stopWhenFinished && e.Finished())
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
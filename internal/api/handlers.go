@@ -1,22 +1,45 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"pa11y-go-wrapper/internal/analysis"
 	"pa11y-go-wrapper/internal/discovery"
+	"pa11y-go-wrapper/internal/webhook"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultAnalyzeTimeout bounds direct analyses unless SetAnalyzeTimeout says otherwise.
+const defaultAnalyzeTimeout = 2 * time.Minute
+
 // Handlers holds the dependencies for the API handlers.
 type Handlers struct {
-	analysisService *analysis.Service
+	analysisService  *analysis.Service
 	discoveryService *discovery.Service
+	workerPool       *analysis.WorkerPool
+	analyzeTimeout   time.Duration
+	webhookService   *webhook.Service
 }
 
 // NewHandlers creates new handlers.
-func NewHandlers(analysisService *analysis.Service, discoveryService *discovery.Service) *Handlers {
-	return &Handlers{analysisService: analysisService, discoveryService: discoveryService}
+func NewHandlers(analysisService *analysis.Service, discoveryService *discovery.Service, workerPool *analysis.WorkerPool) *Handlers {
+	return &Handlers{
+		analysisService:  analysisService,
+		discoveryService: discoveryService,
+		workerPool:       workerPool,
+		analyzeTimeout:   defaultAnalyzeTimeout,
+	}
+}
+
+// SetAnalyzeTimeout sets the maximum duration of a direct analysis.
+func (h *Handlers) SetAnalyzeTimeout(timeout time.Duration) {
+	h.analyzeTimeout = timeout
 }
 
 // DiscoverSiteRequest represents the request body for the /discover endpoint.
@@ -44,11 +67,20 @@ func (h *Handlers) DiscoverSite(c *gin.Context) {
 
 // AnalyzeURLRequest represents the request body for the /analyze endpoint.
 type AnalyzeURLRequest struct {
-	URL    string `json:"url" binding:"required"`
-	Runner string `json:"runner"`
+	URL     string            `json:"url" binding:"required"`
+	Runner  string            `json:"runner"`
+	Runners []string          `json:"runners"`
+	Options *analysis.Options `json:"options"`
+	Auth    *analysis.Auth    `json:"auth"`
+	Profile string            `json:"profile"`
+	// QualityGate sets the limits that decide the gate verdict of the analysis.
+	QualityGate *analysis.QualityGate `json:"qualityGate"`
+	// Record stores the analysis so that it shows up in the queue, history and reports.
+	Record bool `json:"record"`
 }
 
-// AnalyzeURL handles direct analysis of a URL.
+// AnalyzeURL handles direct analysis of a URL. The analysis runs within the request, bounded by
+// the analyze timeout, and is returned with its result.
 func (h *Handlers) AnalyzeURL(c *gin.Context) {
 	var req AnalyzeURLRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -56,14 +88,39 @@ func (h *Handlers) AnalyzeURL(c *gin.Context) {
 		return
 	}
 
-	a := h.analysisService.Create(req.URL, req.Runner)
-	c.JSON(http.StatusAccepted, a)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.analyzeTimeout)
+	defer cancel()
+
+	a, err := h.analysisService.Analyze(ctx, analysis.Request{
+		URL:         req.URL,
+		Runners:     requestedRunners(req.Runner, req.Runners),
+		Options:     req.Options,
+		Auth:        req.Auth,
+		Profile:     req.Profile,
+		QualityGate: req.QualityGate,
+	}, req.Record)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, a)
+	case errors.Is(err, analysis.ErrTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error(), "analysis": a})
+	case errors.Is(err, analysis.ErrAnalysisFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "analysis": a})
+	default:
+		respondAnalysisError(c, err)
+	}
 }
 
 // QueueURLRequest represents the request body for the /queue endpoint.
 type QueueURLRequest struct {
-	URL    string `json:"url" binding:"required"`
-	Runner string `json:"runner"`
+	URL     string            `json:"url" binding:"required"`
+	Runner  string            `json:"runner"`
+	Runners []string          `json:"runners"`
+	Options *analysis.Options `json:"options"`
+	Auth    *analysis.Auth    `json:"auth"`
+	Profile string            `json:"profile"`
+	// QualityGate sets the limits that decide the gate verdict of the analysis.
+	QualityGate *analysis.QualityGate `json:"qualityGate"`
 }
 
 // QueueURL adds a URL to the analysis queue.
@@ -74,8 +131,54 @@ func (h *Handlers) QueueURL(c *gin.Context) {
 		return
 	}
 
-	analysis := h.analysisService.Create(req.URL, req.Runner)
-	c.JSON(http.StatusAccepted, analysis)
+	a, err := h.analysisService.Create(analysis.Request{
+		URL:         req.URL,
+		Runners:     requestedRunners(req.Runner, req.Runners),
+		Options:     req.Options,
+		Auth:        req.Auth,
+		Profile:     req.Profile,
+		QualityGate: req.QualityGate,
+	})
+	if err != nil {
+		respondAnalysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, a)
+}
+
+// requestedRunners combines the single runner and runner list fields of a request.
+func requestedRunners(runner string, runners []string) []string {
+	if runner == "" {
+		return runners
+	}
+	return append([]string{runner}, runners...)
+}
+
+// queueFullRetryAfter is the Retry-After hint, in seconds, sent when the queue is full or too
+// many direct analyses are running.
+const queueFullRetryAfter = 30
+
+// respondAnalysisError maps analysis service errors to HTTP responses.
+func respondAnalysisError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, analysis.ErrQueueFull), errors.Is(err, analysis.ErrBusy):
+		c.Header("Retry-After", strconv.Itoa(queueFullRetryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrInvalidOptions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrInvalidState), errors.Is(err, analysis.ErrProfileExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrInvalidProfile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrProfileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrProfilesDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 // GetQueue returns all analysis tasks.
@@ -95,19 +198,59 @@ func (h *Handlers) GetQueueItem(c *gin.Context) {
 	c.JSON(http.StatusOK, analysis)
 }
 
+// DeleteQueueItem removes an analysis task, taking it off the queue if it is still pending.
+func (h *Handlers) DeleteQueueItem(c *gin.Context) {
+	if err := h.analysisService.Delete(c.Param("id")); err != nil {
+		respondAnalysisError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CancelQueueItem cancels a pending or running analysis task.
+func (h *Handlers) CancelQueueItem(c *gin.Context) {
+	analysis, err := h.analysisService.Cancel(c.Param("id"))
+	if err != nil {
+		respondAnalysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
+
+// RerunQueueItem queues a new analysis of the same page with the same settings.
+func (h *Handlers) RerunQueueItem(c *gin.Context) {
+	analysis, err := h.analysisService.Rerun(c.Param("id"))
+	if err != nil {
+		respondAnalysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, analysis)
+}
+
+// GetURLHistory returns all runs for a URL in chronological order with their issue counts.
+func (h *Handlers) GetURLHistory(c *gin.Context) {
+	url := c.Query("url")
+	if url == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+		return
+	}
+	c.JSON(http.StatusOK, h.analysisService.History(url))
+}
+
+// GetWorkers returns the busy and idle worker counts of the analysis worker pool.
+func (h *Handlers) GetWorkers(c *gin.Context) {
+	if h.workerPool == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no worker pool running"})
+		return
+	}
+	c.JSON(http.StatusOK, h.workerPool.Stats())
+}
+
 // GetCompletedAnalysesHTML returns all completed analysis tasks as an HTML page.
 func (h *Handlers) GetCompletedAnalysesHTML(c *gin.Context) {
-	id := c.Query("id")
-	var analyses []*analysis.Analysis
-	if id != "" {
-		a, ok := h.analysisService.GetByID(id)
-		if !ok {
-			c.String(http.StatusNotFound, "analysis not found")
-			return
-		}
-		analyses = []*analysis.Analysis{a}
-	} else {
-		analyses = h.analysisService.GetCompleted()
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
 	}
 
 	html, err := GenerateHTML(analyses)
@@ -119,26 +262,356 @@ func (h *Handlers) GetCompletedAnalysesHTML(c *gin.Context) {
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
 }
 
-// GetCompletedAnalysesPDF returns all completed analysis tasks as a PDF file.
+// GetCompletedAnalysesPDF returns all completed analysis tasks as a PDF file, or only their
+// executive summary when the summaryOnly query parameter is true.
 func (h *Handlers) GetCompletedAnalysesPDF(c *gin.Context) {
+	var opts PDFOptions
+	if v := c.Query("summaryOnly"); v != "" {
+		summaryOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			c.String(http.StatusBadRequest, "summaryOnly must be true or false")
+			return
+		}
+		opts.SummaryOnly = summaryOnly
+	}
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
+	}
+
+	pdf, err := GeneratePDF(analyses, opts)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate PDF")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// GetCompletedAnalysesSARIF returns all completed analysis tasks as a SARIF 2.1.0 log.
+func (h *Handlers) GetCompletedAnalysesSARIF(c *gin.Context) {
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
+	}
+
+	sarif, err := GenerateSARIF(analyses)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate SARIF")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/sarif+json", sarif)
+}
+
+// GetCompletedAnalysesJUnit returns all completed analysis tasks as a JUnit XML report.
+func (h *Handlers) GetCompletedAnalysesJUnit(c *gin.Context) {
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
+	}
+
+	report, err := GenerateJUnit(analyses)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate JUnit report")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", report)
+}
+
+// GetCompletedAnalysesCSV returns the issues of all completed analysis tasks as a CSV file.
+func (h *Handlers) GetCompletedAnalysesCSV(c *gin.Context) {
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
+	}
+
+	data, err := GenerateCSV(analyses, issueFilter(c))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate CSV")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// GetCompletedAnalysesXLSX returns the issues of all completed analysis tasks as an Excel workbook.
+func (h *Handlers) GetCompletedAnalysesXLSX(c *gin.Context) {
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
+	}
+
+	data, err := GenerateXLSX(analyses, issueFilter(c))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate XLSX")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+}
+
+// GetCompletedAnalysesMarkdown returns a Markdown summary of all completed analysis tasks,
+// shortened to fit the limit query parameter.
+func (h *Handlers) GetCompletedAnalysesMarkdown(c *gin.Context) {
+	limit := DefaultMarkdownLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.String(http.StatusBadRequest, "limit must be a positive number of characters")
+			return
+		}
+		limit = n
+	}
+	analyses, ok := h.reportAnalyses(c)
+	if !ok {
+		return
+	}
+
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(GenerateMarkdown(analyses, limit)))
+}
+
+// reportAnalyses returns the analysis named by the id query parameter, or all completed
+// analyses if there is none. It responds with 404 if the named analysis does not exist.
+func (h *Handlers) reportAnalyses(c *gin.Context) ([]*analysis.Analysis, bool) {
 	id := c.Query("id")
+	if id == "" {
+		return h.analysisService.GetCompleted(), true
+	}
+	a, ok := h.analysisService.GetByID(id)
+	if !ok {
+		c.String(http.StatusNotFound, "analysis not found")
+		return nil, false
+	}
+	return []*analysis.Analysis{a}, true
+}
+
+// issueFilter reads the type and code query parameters, which may be repeated or comma separated.
+func issueFilter(c *gin.Context) IssueFilter {
+	return IssueFilter{Types: queryList(c, "type"), Codes: queryList(c, "code")}
+}
+
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, param := range c.QueryArray(name) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// GetDiff compares the issues of two analyses and returns the new, resolved and unchanged ones.
+func (h *Handlers) GetDiff(c *gin.Context) {
+	diff, status, err := h.loadDiff(c)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// GetDiffHTML returns the comparison of two analyses as an HTML page.
+func (h *Handlers) GetDiffHTML(c *gin.Context) {
+	diff, status, err := h.loadDiff(c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	html, err := GenerateDiffHTML(diff)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate HTML")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+// GetDiffPDF returns the comparison of two analyses as a PDF file.
+func (h *Handlers) GetDiffPDF(c *gin.Context) {
+	diff, status, err := h.loadDiff(c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	pdf, err := GenerateDiffPDF(diff)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate PDF")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// loadDiff diffs the analyses named by the base and head query parameters, which must be
+// completed analyses of the same URL. On failure it returns the HTTP status to respond with.
+func (h *Handlers) loadDiff(c *gin.Context) (*analysis.IssueDiff, int, error) {
+	baseID, headID := c.Query("base"), c.Query("head")
+	if baseID == "" || headID == "" {
+		return nil, http.StatusBadRequest, errors.New("base and head query parameters are required")
+	}
+
+	base, ok := h.analysisService.GetByID(baseID)
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("base analysis %s not found", baseID)
+	}
+	head, ok := h.analysisService.GetByID(headID)
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("head analysis %s not found", headID)
+	}
+	if base.Status != analysis.StatusCompleted || head.Status != analysis.StatusCompleted {
+		return nil, http.StatusConflict, errors.New("both analyses must be completed")
+	}
+	if base.URL != head.URL {
+		return nil, http.StatusBadRequest, fmt.Errorf("base analysis is of %s but head analysis is of %s", base.URL, head.URL)
+	}
+
+	return analysis.Diff(base, head), http.StatusOK, nil
+}
+
+// GetConformance returns the Accessibility Conformance Report of the analyses named by the id
+// query parameter, or of the latest completed analysis of each URL if there is none.
+func (h *Handlers) GetConformance(c *gin.Context) {
+	report, status, err := h.loadConformance(c)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetConformanceHTML returns an Accessibility Conformance Report as an HTML page.
+func (h *Handlers) GetConformanceHTML(c *gin.Context) {
+	report, status, err := h.loadConformance(c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	html, err := GenerateConformanceHTML(report)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate HTML")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+// GetConformancePDF returns an Accessibility Conformance Report as a PDF file.
+func (h *Handlers) GetConformancePDF(c *gin.Context) {
+	report, status, err := h.loadConformance(c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	pdf, err := GenerateConformancePDF(report)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to generate PDF")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// loadConformance builds the conformance report of the analyses named by the id query
+// parameter, which may be repeated or comma separated, against the WCAG version of the version
+// query parameter. On failure it returns the HTTP status to respond with.
+func (h *Handlers) loadConformance(c *gin.Context) (*analysis.ConformanceReport, int, error) {
+	version := c.DefaultQuery("version", analysis.DefaultConformanceVersion)
+
 	var analyses []*analysis.Analysis
-	if id != "" {
+	ids := queryList(c, "id")
+	if len(ids) == 0 {
+		analyses = h.analysisService.LatestCompleted()
+	}
+	for _, id := range ids {
 		a, ok := h.analysisService.GetByID(id)
 		if !ok {
-			c.String(http.StatusNotFound, "analysis not found")
-			return
+			return nil, http.StatusNotFound, fmt.Errorf("analysis %s not found", id)
 		}
-		analyses = []*analysis.Analysis{a}
-	} else {
-		analyses = h.analysisService.GetCompleted()
+		analyses = append(analyses, a)
+	}
+
+	report, err := analysis.NewConformanceReport(analyses, version)
+	switch {
+	case errors.Is(err, analysis.ErrInvalidOptions):
+		return nil, http.StatusBadRequest, err
+	case errors.Is(err, analysis.ErrInvalidState):
+		return nil, http.StatusConflict, err
+	case err != nil:
+		return nil, http.StatusInternalServerError, err
+	}
+	return report, http.StatusOK, nil
+}
+
+// profiles returns the credential profile service, responding with 503 if profiles are disabled.
+func (h *Handlers) profiles(c *gin.Context) (*analysis.ProfileService, bool) {
+	profiles := h.analysisService.Profiles()
+	if profiles == nil {
+		respondAnalysisError(c, fmt.Errorf("%w: set PROFILES_KEY to enable them", analysis.ErrProfilesDisabled))
+		return nil, false
+	}
+	return profiles, true
+}
+
+// CreateProfile stores a new credential profile.
+func (h *Handlers) CreateProfile(c *gin.Context) {
+	profiles, ok := h.profiles(c)
+	if !ok {
+		return
+	}
+	var profile analysis.Profile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	pdf, err := GeneratePDF(analyses)
+	created, err := profiles.Create(&profile)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "failed to generate PDF")
+		respondAnalysisError(c, err)
 		return
 	}
+	c.JSON(http.StatusCreated, created)
+}
 
-	c.Data(http.StatusOK, "application/pdf", pdf)
+// GetProfiles returns all credential profiles with their secrets redacted.
+func (h *Handlers) GetProfiles(c *gin.Context) {
+	profiles, ok := h.profiles(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, profiles.List())
+}
+
+// GetProfile returns a credential profile with its secrets redacted.
+func (h *Handlers) GetProfile(c *gin.Context) {
+	profiles, ok := h.profiles(c)
+	if !ok {
+		return
+	}
+	profile, err := profiles.Get(c.Param("name"))
+	if err != nil {
+		respondAnalysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteProfile removes a credential profile.
+func (h *Handlers) DeleteProfile(c *gin.Context) {
+	profiles, ok := h.profiles(c)
+	if !ok {
+		return
+	}
+	if err := profiles.Delete(c.Param("name")); err != nil {
+		respondAnalysisError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"pa11y-go-wrapper/internal/analysis"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// GenerateJUnit generates a JUnit XML report from a list of analyses. Each analyzed URL is a
+// test suite and each rule code found on it a test case, which fails when the code has
+// error-type issues. Warnings and notices are listed in the output of passing test cases, a
+// page without issues gets a single passing test case and an analysis that did not complete is
+// reported as a test case in error.
+func GenerateJUnit(analyses []*analysis.Analysis) ([]byte, error) {
+	report := junitTestSuites{Name: "pa11y"}
+
+	for _, a := range analyses {
+		suite := junitTestSuite{
+			Name:       a.URL,
+			Time:       fmt.Sprintf("%.3f", float64(a.DurationMs)/1000),
+			Properties: []junitProperty{{Name: "analysisId", Value: a.ID}},
+		}
+		if !a.CreatedAt.IsZero() {
+			suite.Timestamp = a.CreatedAt.UTC().Format("2006-01-02T15:04:05")
+		}
+		if runners := analysisRunners(a); runners != "" {
+			suite.Properties = append(suite.Properties, junitProperty{Name: "runner", Value: runners})
+		}
+
+		if a.Status == analysis.StatusCompleted {
+			suite.Cases = junitTestCases(a)
+		} else {
+			suite.Cases = []junitTestCase{{
+				Name:      "analysis",
+				ClassName: a.URL,
+				Error:     &junitProblem{Message: "analysis " + string(a.Status), Type: string(a.Status), Body: a.ErrorMessage},
+			}}
+			suite.Errors = 1
+		}
+		suite.Tests = len(suite.Cases)
+		for _, tc := range suite.Cases {
+			if tc.Failure != nil {
+				suite.Failures++
+			}
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// junitTestCases groups the issues of a completed analysis by code, in the order the codes
+// were first reported.
+func junitTestCases(a *analysis.Analysis) []junitTestCase {
+	var codes []string
+	byCode := make(map[string][]analysis.Issue)
+	for _, issue := range a.Result {
+		if _, ok := byCode[issue.Code]; !ok {
+			codes = append(codes, issue.Code)
+		}
+		byCode[issue.Code] = append(byCode[issue.Code], issue)
+	}
+
+	cases := make([]junitTestCase, 0, len(codes))
+	for _, code := range codes {
+		issues := byCode[code]
+		tc := junitTestCase{Name: code, ClassName: a.URL}
+
+		var failing []analysis.Issue
+		for _, issue := range issues {
+			if issue.Type == "error" {
+				failing = append(failing, issue)
+			}
+		}
+		if len(failing) > 0 {
+			tc.Failure = &junitProblem{
+				Message: fmt.Sprintf("%d errors: %s", len(failing), failing[0].Message),
+				Type:    "error",
+				Body:    junitIssueList(failing),
+			}
+		} else {
+			tc.SystemOut = junitIssueList(issues)
+		}
+		cases = append(cases, tc)
+	}
+	if len(cases) == 0 {
+		// Report a clean page as one passing test rather than an empty suite.
+		cases = append(cases, junitTestCase{Name: "analysis", ClassName: a.URL})
+	}
+	return cases
+}
+
+// junitIssueList describes each issue on its own lines with its type, selector and context.
+func junitIssueList(issues []analysis.Issue) string {
+	var b strings.Builder
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "[%s] %s\n", issue.Type, issue.Message)
+		if issue.Selector != "" {
+			fmt.Fprintf(&b, "  Selector: %s\n", issue.Selector)
+		}
+		if issue.Context != "" {
+			fmt.Fprintf(&b, "  Context: %s\n", issue.Context)
+		}
+	}
+	return b.String()
+}
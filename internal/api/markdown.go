@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"pa11y-go-wrapper/internal/analysis"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMarkdownLimit is the default maximum length of a Markdown report, in characters. It
+// matches the size limit of GitHub pull request descriptions and comments.
+const DefaultMarkdownLimit = 65536
+
+// markdownTruncatedNote ends a Markdown report whose summary table could not fit at all.
+const markdownTruncatedNote = "_…truncated; see the full report._\n"
+
+// markdownSelectorsPerCode is how many selectors are listed for each rule code before the rest
+// are summarized.
+const markdownSelectorsPerCode = 5
+
+// GenerateMarkdown generates a compact Markdown summary of a list of analyses for pull request
+// comments: a table of errors, warnings and notices per URL followed by a collapsible section
+// per URL with its issues grouped by rule code. The report stays under limit characters
+// (DefaultMarkdownLimit if limit is not positive) and is only ever cut between whole rows and
+// sections, so that no table row or <details> block is left broken: table rows that do not fit
+// are summarized in a last row, room being kept for it and for the total row, and sections that
+// do not fit are shortened to their rule codes and, failing that, left out with a note. When the
+// table cannot fit at all, only a note that the report was truncated is kept.
+func GenerateMarkdown(analyses []*analysis.Analysis, limit int) string {
+	if limit <= 0 {
+		limit = DefaultMarkdownLimit
+	}
+
+	var head strings.Builder
+	head.WriteString("## Accessibility report\n\n")
+	if len(analyses) == 0 {
+		head.WriteString("No analyses to display.\n")
+		return wholeLines(head.String(), limit)
+	}
+
+	var total analysis.IssueCounts
+	rows := make([]string, 0, len(analyses))
+	for _, a := range analyses {
+		counts := analysis.CountIssues(a.Result)
+		total.Errors += counts.Errors
+		total.Warnings += counts.Warnings
+		total.Notices += counts.Notices
+		status := ""
+		if a.Status != analysis.StatusCompleted {
+			status = " (" + string(a.Status) + ")"
+		}
+		rows = append(rows, fmt.Sprintf("| %s%s | %d | %d | %d |\n", markdownCell(a.URL), status, counts.Errors, counts.Warnings, counts.Notices))
+	}
+	tableHead := "| URL | Errors | Warnings | Notices |\n| --- | ---: | ---: | ---: |\n"
+	footer := fmt.Sprintf("| **Total** | **%d** | **%d** | **%d** |\n", total.Errors, total.Warnings, total.Notices)
+	allRowsNote := fmt.Sprintf("| _%d more URLs_ | | | |\n", len(rows))
+	if utf8.RuneCountInString(head.String()+tableHead+allRowsNote+footer) > limit {
+		return wholeLines(head.String()+markdownTruncatedNote, limit)
+	}
+
+	// The summary table comes first; if even that does not fit, drop its last rows.
+	report := head.String() + tableHead
+	budget := limit - utf8.RuneCountInString(report) - utf8.RuneCountInString(footer)
+	for i, row := range rows {
+		note := fmt.Sprintf("| _%d more URLs_ | | | |\n", len(rows)-i)
+		need := utf8.RuneCountInString(row)
+		if i < len(rows)-1 {
+			need += utf8.RuneCountInString(note)
+		}
+		if need > budget {
+			if utf8.RuneCountInString(note) <= budget {
+				report += note
+			}
+			break
+		}
+		report += row
+		budget -= utf8.RuneCountInString(row)
+	}
+	report += footer
+
+	for i, a := range analyses {
+		if len(a.Result) == 0 {
+			continue
+		}
+		omitted := fmt.Sprintf("\n_Details of %d more URLs were left out to stay under %d characters; see the full report._\n", len(analyses)-i, limit)
+		room := limit - utf8.RuneCountInString(report)
+		if i < len(analyses)-1 {
+			room -= utf8.RuneCountInString(omitted)
+		}
+		if section := markdownSection(a, true); utf8.RuneCountInString(section) <= room {
+			report += section
+			continue
+		}
+		if section := markdownSection(a, false); utf8.RuneCountInString(section) <= room {
+			report += section
+			continue
+		}
+		if utf8.RuneCountInString(report)+utf8.RuneCountInString(omitted) <= limit {
+			report += omitted
+		}
+		break
+	}
+	return report
+}
+
+// markdownSection renders the issues of an analysis grouped by rule code, most severe codes
+// first, inside a <details> block. Without withSelectors only the codes and counts are listed.
+func markdownSection(a *analysis.Analysis, withSelectors bool) string {
+	type group struct {
+		code     string
+		typ      string
+		typeCode int
+		message  string
+		issues   []analysis.Issue
+	}
+	var groups []*group
+	byCode := make(map[string]*group)
+	for _, issue := range a.Result {
+		g, ok := byCode[issue.Code]
+		if !ok {
+			g = &group{code: issue.Code, typ: issue.Type, typeCode: issue.TypeCode, message: issue.Message}
+			byCode[issue.Code] = g
+			groups = append(groups, g)
+		}
+		if issue.TypeCode < g.typeCode {
+			g.typ, g.typeCode, g.message = issue.Type, issue.TypeCode, issue.Message
+		}
+		g.issues = append(g.issues, issue)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].typeCode != groups[j].typeCode {
+			return groups[i].typeCode < groups[j].typeCode
+		}
+		return len(groups[i].issues) > len(groups[j].issues)
+	})
+
+	counts := analysis.CountIssues(a.Result)
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n<details>\n<summary><strong>%s</strong>: %d errors, %d warnings, %d notices</summary>\n\n",
+		html.EscapeString(a.URL), counts.Errors, counts.Warnings, counts.Notices)
+	for _, g := range groups {
+		fmt.Fprintf(&b, "#### %s %s (%d)\n\n", markdownCode(g.code), g.typ, len(g.issues))
+		if !withSelectors {
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n\n", markdownText(g.message))
+		for i, issue := range g.issues {
+			if i == markdownSelectorsPerCode {
+				fmt.Fprintf(&b, "- _and %d more_\n", len(g.issues)-i)
+				break
+			}
+			fmt.Fprintf(&b, "- %s\n", markdownCode(issue.Selector))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("</details>\n")
+	return b.String()
+}
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownText(s), "|", `\|`)
+}
+
+// markdownText escapes text so that HTML in it is shown rather than rendered.
+func markdownText(s string) string {
+	return html.EscapeString(strings.Join(strings.Fields(s), " "))
+}
+
+// markdownCode formats s as inline code, using a longer fence when s contains backticks.
+func markdownCode(s string) string {
+	if s == "" {
+		return "_none_"
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// wholeLines cuts s after its last whole line that fits in limit characters.
+func wholeLines(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	cut := string([]rune(s)[:limit])
+	return cut[:strings.LastIndex(cut, "\n")+1]
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"pa11y-go-wrapper/internal/analysis"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func markdownAnalyses(pages, issuesPerPage int) []*analysis.Analysis {
+	var analyses []*analysis.Analysis
+	for p := range pages {
+		a := &analysis.Analysis{ID: fmt.Sprint(p), URL: fmt.Sprintf("https://example.com/page-%d", p), Status: analysis.StatusCompleted}
+		for i := range issuesPerPage {
+			a.Result = append(a.Result, analysis.Issue{
+				Code:     fmt.Sprintf("rule-%d", i%3),
+				Type:     "error",
+				TypeCode: 1,
+				Message:  "Elements must <b>not</b> fail",
+				Selector: fmt.Sprintf("#item-%d", i),
+			})
+		}
+		a.Result = append(a.Result, analysis.Issue{Code: "rule-0", Type: "warning", TypeCode: 2, Message: "Check", Selector: "p"})
+		analyses = append(analyses, a)
+	}
+	return analyses
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	md := GenerateMarkdown(markdownAnalyses(2, 9), 0)
+
+	assert.Contains(t, md, "| https://example.com/page-0 | 9 | 1 | 0 |")
+	assert.Contains(t, md, "| **Total** | **18** | **2** | **0** |")
+	assert.Equal(t, 2, strings.Count(md, "<details>"))
+	assert.Contains(t, md, "<summary><strong>https://example.com/page-1</strong>: 9 errors, 1 warnings, 0 notices</summary>")
+	assert.Contains(t, md, "#### `rule-0` error (4)")
+	assert.Contains(t, md, "Elements must &lt;b&gt;not&lt;/b&gt; fail")
+	assert.Contains(t, md, "- `#item-0`")
+	assert.NotContains(t, md, "_and")
+}
+
+func TestGenerateMarkdownTruncates(t *testing.T) {
+	analyses := markdownAnalyses(30, 20)
+	full := GenerateMarkdown(analyses, 0)
+	assert.Contains(t, full, "- _and 2 more_", "selectors beyond the per-code limit are summarized")
+
+	limits := []int{600, 3000, utf8.RuneCountInString(full) - 1}
+	for limit := 60; limit <= 400; limit += 7 {
+		limits = append(limits, limit)
+	}
+	for _, limit := range limits {
+		md := GenerateMarkdown(analyses, limit)
+		assert.LessOrEqual(t, utf8.RuneCountInString(md), limit)
+		assert.Equal(t, strings.Count(md, "<details>"), strings.Count(md, "</details>"), "sections are never cut in half")
+		for _, line := range strings.Split(strings.TrimSuffix(md, "\n"), "\n") {
+			if strings.HasPrefix(line, "|") {
+				assert.True(t, strings.HasSuffix(line, "|"), "table rows are never cut in half: %q at limit %d", line, limit)
+			}
+		}
+		if limit <= 400 {
+			assert.True(t, strings.Contains(md, "truncated") || strings.Contains(md, "more URLs"), "limit %d: truncation is noted", limit)
+		}
+	}
+
+	md := GenerateMarkdown(analyses, 100)
+	assert.Equal(t, "## Accessibility report\n\n"+markdownTruncatedNote, md, "a table that cannot fit is left out whole")
+
+	md = GenerateMarkdown(analyses, 3000)
+	assert.Contains(t, md, "| **Total** | **600** | **30** | **0** |")
+	assert.Contains(t, md, "more URLs were left out")
+
+	md = GenerateMarkdown(analyses, 600)
+	assert.Contains(t, md, "more URLs_ | | | |", "table rows that do not fit are summarized")
+	assert.Contains(t, md, "| **Total** |")
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"html"
 	"pa11y-go-wrapper/internal/analysis"
+	"strings"
 
 	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/page"
 	"github.com/johnfercher/maroto/v2/pkg/components/row"
 	"github.com/johnfercher/maroto/v2/pkg/components/text"
 	"github.com/johnfercher/maroto/v2/pkg/consts/align"
@@ -30,6 +32,8 @@ func GenerateHTML(analyses []*analysis.Analysis) (string, error) {
 		return builder.String(), nil
 	}
 
+	writeCriteriaSummaryHTML(&builder, analysis.GroupByCriterion(allIssues(analyses)))
+
 	for _, a := range analyses {
 		builder.WriteString("<section style='margin-bottom:24px'>")
 		builder.WriteString("<h2>" + html.EscapeString(a.URL) + "</h2>")
@@ -37,8 +41,17 @@ func GenerateHTML(analyses []*analysis.Analysis) (string, error) {
 		builder.WriteString("<tr><th align='left'>ID</th><td>" + html.EscapeString(a.ID) + "</td></tr>")
 		builder.WriteString("<tr><th align='left'>URL</th><td>" + html.EscapeString(a.URL) + "</td></tr>")
 		builder.WriteString("<tr><th align='left'>Status</th><td>" + html.EscapeString(string(a.Status)) + "</td></tr>")
-		if a.Runner != "" {
-			builder.WriteString("<tr><th align='left'>Runner</th><td>" + html.EscapeString(a.Runner) + "</td></tr>")
+		if runners := analysisRunners(a); runners != "" {
+			builder.WriteString("<tr><th align='left'>Runner</th><td>" + html.EscapeString(runners) + "</td></tr>")
+		}
+		if a.Profile != "" {
+			builder.WriteString("<tr><th align='left'>Profile</th><td>" + html.EscapeString(a.Profile) + "</td></tr>")
+		}
+		if auth := a.Auth.Summary(); auth != "" {
+			builder.WriteString("<tr><th align='left'>Authentication</th><td>" + html.EscapeString(auth) + "</td></tr>")
+		}
+		if gate := gateSummary(a); gate != "" {
+			builder.WriteString("<tr><th align='left'>Quality Gate</th><td>" + html.EscapeString(gate) + "</td></tr>")
 		}
 		if a.ErrorMessage != "" {
 			builder.WriteString("<tr><th align='left'>Error</th><td>" + html.EscapeString(a.ErrorMessage) + "</td></tr>")
@@ -51,30 +64,166 @@ func GenerateHTML(analyses []*analysis.Analysis) (string, error) {
 		builder.WriteString("<h3>Issues (" + fmt.Sprintf("%d", len(a.Result)) + ")</h3>")
 		if len(a.Result) == 0 {
 			builder.WriteString("<p>No issues found.</p>")
+		}
+		for _, group := range analysis.GroupByCriterion(a.Result) {
+			builder.WriteString("<h4>" + html.EscapeString(criterionHeading(group)) + "</h4>")
+			writeIssuesTableHTML(&builder, group.Issues)
+		}
+		builder.WriteString("</section>")
+	}
+
+	builder.WriteString("</body></html>")
+
+	return builder.String(), nil
+}
+
+// writeCriteriaSummaryHTML writes a table counting issues per WCAG success criterion.
+func writeCriteriaSummaryHTML(builder *bytes.Buffer, groups []analysis.CriterionGroup) {
+	if len(groups) == 0 {
+		return
+	}
+
+	builder.WriteString("<h2>Issues by WCAG success criterion</h2>")
+	builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
+	builder.WriteString("<tr>" +
+		"<th>Principle</th>" +
+		"<th>Success Criterion</th>" +
+		"<th>Level</th>" +
+		"<th>Errors</th>" +
+		"<th>Warnings</th>" +
+		"<th>Notices</th>" +
+		"<th>Total</th>" +
+		"</tr>")
+	for _, group := range groups {
+		builder.WriteString("<tr>")
+		builder.WriteString("<td>" + html.EscapeString(criterionPrinciple(group.Criterion)) + "</td>")
+		if group.Criterion.Number != "" {
+			builder.WriteString("<td><a href='" + html.EscapeString(group.Criterion.URL()) + "'>" + html.EscapeString(criterionTitle(group.Criterion)) + "</a></td>")
 		} else {
-			builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
-			builder.WriteString("<tr>" +
-				"<th>#</th>" +
-				"<th>Code</th>" +
-				"<th>Message</th>" +
-				"<th>Type</th>" +
-				"<th>TypeCode</th>" +
-				"<th>Selector</th>" +
-				"<th>Context</th>" +
-				"</tr>")
-			for idx, issue := range a.Result {
-				builder.WriteString("<tr>")
-				builder.WriteString("<td>" + fmt.Sprintf("%d", idx+1) + "</td>")
-				builder.WriteString("<td>" + html.EscapeString(issue.Code) + "</td>")
-				builder.WriteString("<td>" + html.EscapeString(issue.Message) + "</td>")
-				builder.WriteString("<td>" + html.EscapeString(issue.Type) + "</td>")
-				builder.WriteString("<td>" + fmt.Sprintf("%d", issue.TypeCode) + "</td>")
-				builder.WriteString("<td>" + html.EscapeString(issue.Selector) + "</td>")
-				builder.WriteString("<td>" + html.EscapeString(issue.Context) + "</td>")
-				builder.WriteString("</tr>")
-			}
-			builder.WriteString("</table>")
+			builder.WriteString("<td>" + html.EscapeString(criterionTitle(group.Criterion)) + "</td>")
 		}
+		builder.WriteString("<td>" + html.EscapeString(group.Criterion.Level) + "</td>")
+		builder.WriteString("<td>" + fmt.Sprintf("%d", group.Counts.Errors) + "</td>")
+		builder.WriteString("<td>" + fmt.Sprintf("%d", group.Counts.Warnings) + "</td>")
+		builder.WriteString("<td>" + fmt.Sprintf("%d", group.Counts.Notices) + "</td>")
+		builder.WriteString("<td>" + fmt.Sprintf("%d", group.Counts.Total) + "</td>")
+		builder.WriteString("</tr>")
+	}
+	builder.WriteString("</table>")
+}
+
+// writeIssuesTableHTML writes issues as an HTML table.
+func writeIssuesTableHTML(builder *bytes.Buffer, issues []analysis.Issue) {
+	if len(issues) == 0 {
+		builder.WriteString("<p>No issues found.</p>")
+		return
+	}
+
+	builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
+	builder.WriteString("<tr>" +
+		"<th>#</th>" +
+		"<th>Code</th>" +
+		"<th>Message</th>" +
+		"<th>Type</th>" +
+		"<th>TypeCode</th>" +
+		"<th>Runner</th>" +
+		"<th>Selector</th>" +
+		"<th>Context</th>" +
+		"</tr>")
+	for idx, issue := range issues {
+		builder.WriteString("<tr>")
+		builder.WriteString("<td>" + fmt.Sprintf("%d", idx+1) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(issue.Code) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(issue.Message) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(issue.Type) + "</td>")
+		builder.WriteString("<td>" + fmt.Sprintf("%d", issue.TypeCode) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(issueRunners(issue)) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(issue.Selector) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(issue.Context) + "</td>")
+		builder.WriteString("</tr>")
+	}
+	builder.WriteString("</table>")
+}
+
+// analysisRunners returns the runners an analysis was requested with, comma separated.
+func analysisRunners(a *analysis.Analysis) string {
+	if len(a.Runners) > 0 {
+		return strings.Join(a.Runners, ", ")
+	}
+	return a.Runner
+}
+
+// issueRunners returns the runners that reported an issue, comma separated.
+func issueRunners(issue analysis.Issue) string {
+	if len(issue.Runners) > 0 {
+		return strings.Join(issue.Runners, ", ")
+	}
+	return issue.Runner
+}
+
+// allIssues returns the issues of every analysis.
+func allIssues(analyses []*analysis.Analysis) []analysis.Issue {
+	var issues []analysis.Issue
+	for _, a := range analyses {
+		issues = append(issues, a.Result...)
+	}
+	return issues
+}
+
+// criterionTitle names a success criterion, e.g. "1.4.3 Contrast (Minimum)", or the group of
+// issues without one.
+func criterionTitle(c analysis.Criterion) string {
+	if c.Number == "" {
+		return "Other issues"
+	}
+	return c.Number + " " + c.Name
+}
+
+// criterionHeading titles the issues of a success criterion in a report, e.g.
+// "1.4.3 Contrast (Minimum) - Level AA (3)".
+func criterionHeading(group analysis.CriterionGroup) string {
+	if group.Criterion.Level == "" {
+		return fmt.Sprintf("%s (%d)", criterionTitle(group.Criterion), len(group.Issues))
+	}
+	return fmt.Sprintf("%s - Level %s (%d)", criterionTitle(group.Criterion), group.Criterion.Level, len(group.Issues))
+}
+
+// criterionPrinciple returns the principle of a success criterion, if it has one.
+func criterionPrinciple(c analysis.Criterion) string {
+	if c.Number == "" {
+		return ""
+	}
+	return c.Principle()
+}
+
+// gateSummary describes the quality gate verdict of an analysis with its reasons, e.g.
+// "failed: 3 errors exceed the maximum of 0".
+func gateSummary(a *analysis.Analysis) string {
+	if a.Gate == "" {
+		return ""
+	}
+	if len(a.GateReasons) == 0 {
+		return string(a.Gate)
+	}
+	return string(a.Gate) + ": " + strings.Join(a.GateReasons, "; ")
+}
+
+// GenerateDiffHTML generates an HTML document comparing the issues of two analyses.
+func GenerateDiffHTML(d *analysis.IssueDiff) (string, error) {
+	var builder bytes.Buffer
+
+	builder.WriteString("<html><head><title>Accessibility Diff</title><meta charset='utf-8'></head><body>")
+	builder.WriteString("<h1>Accessibility Diff</h1>")
+
+	builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
+	builder.WriteString("<tr><th align='left'>Base</th><td>" + html.EscapeString(d.BaseID) + "</td><td>" + html.EscapeString(d.BaseURL) + "</td></tr>")
+	builder.WriteString("<tr><th align='left'>Head</th><td>" + html.EscapeString(d.HeadID) + "</td><td>" + html.EscapeString(d.HeadURL) + "</td></tr>")
+	builder.WriteString("</table>")
+
+	for _, section := range diffSections(d) {
+		builder.WriteString("<section style='margin-bottom:24px'>")
+		builder.WriteString("<h2>" + section.title + " (" + fmt.Sprintf("%d", len(section.issues)) + ")</h2>")
+		writeIssuesTableHTML(&builder, section.issues)
 		builder.WriteString("</section>")
 	}
 
@@ -83,8 +232,79 @@ func GenerateHTML(analyses []*analysis.Analysis) (string, error) {
 	return builder.String(), nil
 }
 
-// GeneratePDF generates a PDF document from a list of analyses.
-func GeneratePDF(analyses []*analysis.Analysis) ([]byte, error) {
+// PDFOptions configures the PDF report.
+type PDFOptions struct {
+	// SummaryOnly leaves out everything after the executive summary page.
+	SummaryOnly bool
+}
+
+// GenerateConformanceHTML generates an HTML Accessibility Conformance Report with a table of
+// success criteria per conformance level.
+func GenerateConformanceHTML(r *analysis.ConformanceReport) (string, error) {
+	var builder bytes.Buffer
+
+	builder.WriteString("<html><head><title>Accessibility Conformance Report</title><meta charset='utf-8'></head><body>")
+	builder.WriteString("<h1>Accessibility Conformance Report</h1>")
+	builder.WriteString("<p>WCAG " + html.EscapeString(r.Version) + " Level A and AA, generated on " + r.GeneratedAt.Format("2006-01-02") + " from automated tests. " +
+		"Criteria that the runners used did not test on every page are Not Tested and those no automated test covers are Not Evaluated; they and every warning or notice need a manual review.</p>")
+
+	builder.WriteString("<h2>Evaluated pages (" + fmt.Sprintf("%d", len(r.Pages)) + ")</h2>")
+	builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
+	builder.WriteString("<tr><th>URL</th><th>Analysis ID</th><th>Analyzed At</th></tr>")
+	for _, p := range r.Pages {
+		builder.WriteString("<tr>")
+		builder.WriteString("<td>" + html.EscapeString(p.URL) + "</td>")
+		builder.WriteString("<td>" + html.EscapeString(p.AnalysisID) + "</td>")
+		builder.WriteString("<td>" + p.AnalyzedAt.Format("2006-01-02 15:04:05") + "</td>")
+		builder.WriteString("</tr>")
+	}
+	builder.WriteString("</table>")
+
+	builder.WriteString("<h2>Summary</h2>")
+	builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
+	for _, conformance := range conformanceLevels {
+		builder.WriteString("<tr><th align='left'>" + html.EscapeString(string(conformance)) + "</th><td>" + fmt.Sprintf("%d", r.Summary[conformance]) + "</td></tr>")
+	}
+	builder.WriteString("</table>")
+
+	for _, level := range []string{"A", "AA"} {
+		builder.WriteString("<h2>Table: Success Criteria, Level " + level + "</h2>")
+		builder.WriteString("<table border='1' cellpadding='4' cellspacing='0'>")
+		builder.WriteString("<tr>" +
+			"<th>Criteria</th>" +
+			"<th>Conformance Level</th>" +
+			"<th>Remarks and Explanations</th>" +
+			"</tr>")
+		for _, cc := range r.Criteria {
+			if cc.Criterion.Level != level {
+				continue
+			}
+			builder.WriteString("<tr>")
+			builder.WriteString("<td><a href='" + html.EscapeString(cc.Criterion.URL()) + "'>" + html.EscapeString(criterionTitle(cc.Criterion)) + "</a></td>")
+			builder.WriteString("<td>" + html.EscapeString(string(cc.Conformance)) + "</td>")
+			builder.WriteString("<td>" + html.EscapeString(cc.Remarks) + "</td>")
+			builder.WriteString("</tr>")
+		}
+		builder.WriteString("</table>")
+	}
+
+	builder.WriteString("</body></html>")
+
+	return builder.String(), nil
+}
+
+// conformanceLevels lists the conformance levels of a conformance report, from best to worst.
+var conformanceLevels = []analysis.Conformance{
+	analysis.ConformanceSupports,
+	analysis.ConformancePartiallySupports,
+	analysis.ConformanceDoesNotSupport,
+	analysis.ConformanceNotTested,
+	analysis.ConformanceNotEvaluated,
+}
+
+// GeneratePDF generates a PDF document from a list of analyses. Its first page is an executive
+// summary of the analyses, followed by the issues of each of them unless opts.SummaryOnly is set.
+func GeneratePDF(analyses []*analysis.Analysis, opts PDFOptions) ([]byte, error) {
 	cfg := config.NewBuilder().
 		WithPageNumber().
 		WithLeftMargin(10).
@@ -95,19 +315,32 @@ func GeneratePDF(analyses []*analysis.Analysis) ([]byte, error) {
 	mrt := maroto.New(cfg)
 	m := maroto.NewMetricsDecorator(mrt)
 
-	m.AddRows(text.NewRow(10, "Accessibility Analyses", props.Text{
+	summaryRows, err := getExecutiveSummaryRows(analyses)
+	if err != nil {
+		return nil, err
+	}
+	summary := page.New().Add(text.NewRow(10, "Accessibility Analyses", props.Text{
 		Top:   3,
 		Style: fontstyle.Bold,
 		Align: align.Center,
 	}))
+	summary.Add(summaryRows...)
+	m.AddPages(summary)
 
-	// Add each analysis as a section
-	for idx, a := range analyses {
-		m.AddRows(getAnalysisSectionRows(a)...)
-		// Add a spacer between analyses (except after the last one)
-		if idx < len(analyses)-1 {
-			m.AddRows(text.NewRow(5, " ", props.Text{}))
+	if !opts.SummaryOnly {
+		// The details start on a new page after the summary.
+		details := page.New()
+		details.Add(getCriteriaSummaryRows(analysis.GroupByCriterion(allIssues(analyses)))...)
+
+		// Add each analysis as a section
+		for idx, a := range analyses {
+			details.Add(getAnalysisSectionRows(a)...)
+			// Add a spacer between analyses (except after the last one)
+			if idx < len(analyses)-1 {
+				details.Add(text.NewRow(5, " ", props.Text{}))
+			}
 		}
+		m.AddPages(details)
 	}
 
 	document, err := m.Generate()
@@ -137,10 +370,28 @@ func getAnalysisSectionRows(a *analysis.Analysis) []core.Row {
 		text.NewCol(2, "Status:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
 		text.NewCol(10, string(a.Status), props.Text{Size: 9, Align: align.Left}),
 	))
-	if a.Runner != "" {
+	if runners := analysisRunners(a); runners != "" {
 		rows = append(rows, row.New(5).Add(
 			text.NewCol(2, "Runner:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
-			text.NewCol(10, a.Runner, props.Text{Size: 9, Align: align.Left}),
+			text.NewCol(10, runners, props.Text{Size: 9, Align: align.Left}),
+		))
+	}
+	if a.Profile != "" {
+		rows = append(rows, row.New(5).Add(
+			text.NewCol(2, "Profile:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(10, a.Profile, props.Text{Size: 9, Align: align.Left}),
+		))
+	}
+	if auth := a.Auth.Summary(); auth != "" {
+		rows = append(rows, row.New(5).Add(
+			text.NewCol(2, "Authentication:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(10, auth, props.Text{Size: 9, Align: align.Left}),
+		))
+	}
+	if gate := gateSummary(a); gate != "" {
+		rows = append(rows, row.New(5).Add(
+			text.NewCol(2, "Quality Gate:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(10, gate, props.Text{Size: 9, Align: align.Left}),
 		))
 	}
 	if a.ErrorMessage != "" {
@@ -163,8 +414,58 @@ func getAnalysisSectionRows(a *analysis.Analysis) []core.Row {
 
 	// Issues header
 	rows = append(rows, text.NewRow(7, fmt.Sprintf("Issues (%d)", len(a.Result)), props.Text{Style: fontstyle.Bold, Align: align.Left}))
-
 	if len(a.Result) == 0 {
+		rows = append(rows, getIssueTableRows(nil)...)
+	}
+	for _, group := range analysis.GroupByCriterion(a.Result) {
+		rows = append(rows, text.NewRow(6, criterionHeading(group), props.Text{Size: 9, Top: 1, Style: fontstyle.Bold, Align: align.Left}))
+		rows = append(rows, getIssueTableRows(group.Issues)...)
+	}
+
+	return rows
+}
+
+// getCriteriaSummaryRows renders a PDF table counting issues per WCAG success criterion.
+func getCriteriaSummaryRows(groups []analysis.CriterionGroup) []core.Row {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	rows := []core.Row{
+		text.NewRow(8, "Issues by WCAG success criterion", props.Text{Style: fontstyle.Bold, Align: align.Left}),
+		row.New(5).Add(
+			text.NewCol(2, "Principle", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(5, "Success Criterion", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Level", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Errors", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Warnings", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Notices", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Total", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+		),
+	}
+	for i, group := range groups {
+		r := row.New(5).Add(
+			text.NewCol(2, criterionPrinciple(group.Criterion), props.Text{Size: 8, Align: align.Left}),
+			text.NewCol(5, criterionTitle(group.Criterion), props.Text{Size: 8, Align: align.Left}),
+			text.NewCol(1, group.Criterion.Level, props.Text{Size: 8, Align: align.Center}),
+			text.NewCol(1, fmt.Sprintf("%d", group.Counts.Errors), props.Text{Size: 8, Align: align.Center}),
+			text.NewCol(1, fmt.Sprintf("%d", group.Counts.Warnings), props.Text{Size: 8, Align: align.Center}),
+			text.NewCol(1, fmt.Sprintf("%d", group.Counts.Notices), props.Text{Size: 8, Align: align.Center}),
+			text.NewCol(1, fmt.Sprintf("%d", group.Counts.Total), props.Text{Size: 8, Align: align.Center}),
+		)
+		if i%2 == 0 {
+			r.WithStyle(&props.Cell{BackgroundColor: getGrayColor()})
+		}
+		rows = append(rows, r)
+	}
+	return append(rows, text.NewRow(5, " ", props.Text{}))
+}
+
+// getIssueTableRows renders issues as a PDF table.
+func getIssueTableRows(issues []analysis.Issue) []core.Row {
+	rows := []core.Row{}
+
+	if len(issues) == 0 {
 		rows = append(rows, text.NewRow(5, "No issues found.", props.Text{Align: align.Left}))
 		return rows
 	}
@@ -174,20 +475,22 @@ func getAnalysisSectionRows(a *analysis.Analysis) []core.Row {
 		text.NewCol(1, "#", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
 		text.NewCol(2, "Code", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
 		text.NewCol(3, "Message", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
-		text.NewCol(2, "Type", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+		text.NewCol(1, "Type", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
 		text.NewCol(1, "TypeCode", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+		text.NewCol(1, "Runner", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
 		text.NewCol(3, "Selector", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
 	)
 	rows = append(rows, headers)
 
 	// Issue rows
-	for i, issue := range a.Result {
+	for i, issue := range issues {
 		ir := row.New(5).Add(
 			text.NewCol(1, fmt.Sprintf("%d", i+1), props.Text{Size: 8, Align: align.Center}),
 			text.NewCol(2, issue.Code, props.Text{Size: 8, Align: align.Left}),
 			text.NewCol(3, issue.Message, props.Text{Size: 8, Align: align.Left}),
-			text.NewCol(2, issue.Type, props.Text{Size: 8, Align: align.Left}),
+			text.NewCol(1, issue.Type, props.Text{Size: 8, Align: align.Left}),
 			text.NewCol(1, fmt.Sprintf("%d", issue.TypeCode), props.Text{Size: 8, Align: align.Center}),
+			text.NewCol(1, issueRunners(issue), props.Text{Size: 8, Align: align.Left}),
 			text.NewCol(3, issue.Selector, props.Text{Size: 8, Align: align.Left}),
 		)
 		if i%2 == 0 {
@@ -199,6 +502,149 @@ func getAnalysisSectionRows(a *analysis.Analysis) []core.Row {
 	return rows
 }
 
+// GenerateDiffPDF generates a PDF document comparing the issues of two analyses.
+func GenerateDiffPDF(d *analysis.IssueDiff) ([]byte, error) {
+	cfg := config.NewBuilder().
+		WithPageNumber().
+		WithLeftMargin(10).
+		WithTopMargin(15).
+		WithRightMargin(10).
+		Build()
+
+	mrt := maroto.New(cfg)
+	m := maroto.NewMetricsDecorator(mrt)
+
+	m.AddRows(text.NewRow(10, "Accessibility Diff", props.Text{
+		Top:   3,
+		Style: fontstyle.Bold,
+		Align: align.Center,
+	}))
+
+	m.AddRows(
+		row.New(5).Add(
+			text.NewCol(2, "Base:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(10, d.BaseID+"  "+d.BaseURL, props.Text{Size: 9, Align: align.Left}),
+		),
+		row.New(5).Add(
+			text.NewCol(2, "Head:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(10, d.HeadID+"  "+d.HeadURL, props.Text{Size: 9, Align: align.Left}),
+		),
+	)
+
+	for _, section := range diffSections(d) {
+		m.AddRows(text.NewRow(4, " ", props.Text{}))
+		m.AddRows(text.NewRow(7, fmt.Sprintf("%s (%d)", section.title, len(section.issues)), props.Text{Style: fontstyle.Bold, Align: align.Left}))
+		m.AddRows(getIssueTableRows(section.issues)...)
+	}
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	return document.GetBytes(), nil
+}
+
+// GenerateConformancePDF generates a PDF Accessibility Conformance Report with a table of
+// success criteria per conformance level.
+func GenerateConformancePDF(r *analysis.ConformanceReport) ([]byte, error) {
+	cfg := config.NewBuilder().
+		WithPageNumber().
+		WithLeftMargin(10).
+		WithTopMargin(15).
+		WithRightMargin(10).
+		Build()
+
+	mrt := maroto.New(cfg)
+	m := maroto.NewMetricsDecorator(mrt)
+
+	m.AddRows(text.NewRow(10, "Accessibility Conformance Report", props.Text{
+		Top:   3,
+		Style: fontstyle.Bold,
+		Align: align.Center,
+	}))
+	m.AddRows(text.NewRow(10, fmt.Sprintf("WCAG %s Level A and AA, generated on %s from automated tests. "+
+		"Criteria that the runners used did not test on every page are Not Tested and those no automated test covers are Not Evaluated; they and every warning or notice need a manual review.",
+		r.Version, r.GeneratedAt.Format("2006-01-02")), props.Text{Size: 9, Align: align.Left}))
+
+	m.AddRows(text.NewRow(8, fmt.Sprintf("Evaluated pages (%d)", len(r.Pages)), props.Text{Style: fontstyle.Bold, Align: align.Left}))
+	for _, p := range r.Pages {
+		m.AddRows(row.New(5).Add(
+			text.NewCol(8, p.URL, props.Text{Size: 8, Align: align.Left}),
+			text.NewCol(4, p.AnalyzedAt.Format("2006-01-02 15:04:05"), props.Text{Size: 8, Align: align.Left}),
+		))
+	}
+
+	m.AddRows(text.NewRow(4, " ", props.Text{}))
+	m.AddRows(text.NewRow(8, "Summary", props.Text{Style: fontstyle.Bold, Align: align.Left}))
+	for _, conformance := range conformanceLevels {
+		m.AddRows(row.New(5).Add(
+			text.NewCol(3, string(conformance)+":", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(9, fmt.Sprintf("%d", r.Summary[conformance]), props.Text{Size: 9, Align: align.Left}),
+		))
+	}
+
+	for _, level := range []string{"A", "AA"} {
+		m.AddRows(text.NewRow(4, " ", props.Text{}))
+		m.AddRows(text.NewRow(8, "Table: Success Criteria, Level "+level, props.Text{Style: fontstyle.Bold, Align: align.Left}))
+		m.AddRows(row.New(5).Add(
+			text.NewCol(4, "Criteria", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(3, "Conformance Level", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(5, "Remarks and Explanations", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+		))
+		i := 0
+		for _, cc := range r.Criteria {
+			if cc.Criterion.Level != level {
+				continue
+			}
+			cr := row.New(8).Add(
+				text.NewCol(4, criterionTitle(cc.Criterion), props.Text{Size: 8, Top: 1, Align: align.Left}),
+				text.NewCol(3, string(cc.Conformance), props.Text{Size: 8, Top: 1, Style: fontstyle.Bold, Align: align.Left, Color: getConformanceColor(cc.Conformance)}),
+				text.NewCol(5, cc.Remarks, props.Text{Size: 8, Top: 1, Align: align.Left}),
+			)
+			if i%2 == 0 {
+				cr.WithStyle(&props.Cell{BackgroundColor: getGrayColor()})
+			}
+			m.AddRows(cr)
+			i++
+		}
+	}
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	return document.GetBytes(), nil
+}
+
+// getConformanceColor returns the text color of a conformance level.
+func getConformanceColor(conformance analysis.Conformance) *props.Color {
+	switch conformance {
+	case analysis.ConformanceDoesNotSupport:
+		return getErrorColor()
+	case analysis.ConformancePartiallySupports:
+		return getWarningColor()
+	case analysis.ConformanceSupports:
+		return &props.Color{Red: 46, Green: 125, Blue: 50}
+	default:
+		return &props.Color{Red: 97, Green: 97, Blue: 97}
+	}
+}
+
+type diffSection struct {
+	title  string
+	issues []analysis.Issue
+}
+
+func diffSections(d *analysis.IssueDiff) []diffSection {
+	return []diffSection{
+		{title: "New issues", issues: d.New},
+		{title: "Resolved issues", issues: d.Resolved},
+		{title: "Unchanged issues", issues: d.Unchanged},
+	}
+}
+
 func getGrayColor() *props.Color {
 	return &props.Color{
 		Red:   200,
@@ -18,8 +18,35 @@ func NewRouter(h *Handlers, frontendAssets embed.FS) *gin.Engine {
 		api.POST("/queue", h.QueueURL)
 		api.GET("/queue", h.GetQueue)
 		api.GET("/queue/:id", h.GetQueueItem)
+		api.GET("/queue/:id/events", h.GetQueueItemEvents)
+		api.DELETE("/queue/:id", h.DeleteQueueItem)
+		api.POST("/queue/:id/cancel", h.CancelQueueItem)
+		api.POST("/queue/:id/rerun", h.RerunQueueItem)
+		api.GET("/urls/history", h.GetURLHistory)
+		api.GET("/workers", h.GetWorkers)
+		api.GET("/events", h.GetEvents)
 		api.GET("/completed/html", h.GetCompletedAnalysesHTML)
 		api.GET("/completed/pdf", h.GetCompletedAnalysesPDF)
+		api.GET("/completed/sarif", h.GetCompletedAnalysesSARIF)
+		api.GET("/completed/junit", h.GetCompletedAnalysesJUnit)
+		api.GET("/completed/csv", h.GetCompletedAnalysesCSV)
+		api.GET("/completed/xlsx", h.GetCompletedAnalysesXLSX)
+		api.GET("/completed/markdown", h.GetCompletedAnalysesMarkdown)
+		api.GET("/diff", h.GetDiff)
+		api.GET("/diff/html", h.GetDiffHTML)
+		api.GET("/diff/pdf", h.GetDiffPDF)
+		api.GET("/conformance", h.GetConformance)
+		api.GET("/conformance/html", h.GetConformanceHTML)
+		api.GET("/conformance/pdf", h.GetConformancePDF)
+		api.POST("/profiles", h.CreateProfile)
+		api.GET("/profiles", h.GetProfiles)
+		api.GET("/profiles/:name", h.GetProfile)
+		api.DELETE("/profiles/:name", h.DeleteProfile)
+		api.POST("/webhooks", h.CreateWebhook)
+		api.GET("/webhooks", h.GetWebhooks)
+		api.GET("/webhooks/:id", h.GetWebhook)
+		api.DELETE("/webhooks/:id", h.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
 		api.POST("/discover", h.DiscoverSite)
 	}
 
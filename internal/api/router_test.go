@@ -2,13 +2,22 @@ package api
 
 import (
 	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"pa11y-go-wrapper/internal/analysis"
 	"pa11y-go-wrapper/internal/discovery"
+	"pa11y-go-wrapper/internal/webhook"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
 )
 
 // Embed local test frontend assets so fs.Sub works in router
@@ -19,13 +28,17 @@ var frontendAssets embed.FS
 func TestCompletedHTML(t *testing.T) {
 	// Create a new analysis service and add a completed analysis
 	service := analysis.NewService(10)
-	a := service.Create("http://example.com", "")
-	service.UpdateResult(a.ID, analysis.StatusCompleted, nil, "")
+	a, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(a.ID, analysis.StatusCompleted, []analysis.Issue{
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error", Message: "Low contrast"},
+		{Code: "region", Type: "notice", Message: "Content not in a landmark"},
+	}, "")
 
 	// Create a new router
 	discoveryService, err := discovery.NewService()
 	assert.NoError(t, err)
-	handlers := NewHandlers(service, discoveryService)
+	handlers := NewHandlers(service, discoveryService, nil)
 	router := NewRouter(handlers, frontendAssets)
 
 	// Create a new request to the /completed/html endpoint
@@ -36,18 +49,22 @@ func TestCompletedHTML(t *testing.T) {
 	// Check that the response is correct
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "<h1>Accessibility Analyses</h1>")
+	assert.Contains(t, w.Body.String(), "<h2>Issues by WCAG success criterion</h2>")
+	assert.Contains(t, w.Body.String(), "<h4>1.4.3 Contrast (Minimum) - Level AA (1)</h4>")
+	assert.Contains(t, w.Body.String(), "<h4>Other issues (1)</h4>")
 }
 
 func TestCompletedPDF(t *testing.T) {
 	// Create a new analysis service and add a completed analysis
 	service := analysis.NewService(10)
-	a := service.Create("http://example.com", "")
+	a, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
 	service.UpdateResult(a.ID, analysis.StatusCompleted, nil, "")
 
 	// Create a new router
 	discoveryService, err := discovery.NewService()
 	assert.NoError(t, err)
-	handlers := NewHandlers(service, discoveryService)
+	handlers := NewHandlers(service, discoveryService, nil)
 	router := NewRouter(handlers, frontendAssets)
 
 	// Create a new request to the /completed/pdf endpoint
@@ -58,4 +75,510 @@ func TestCompletedPDF(t *testing.T) {
 	// Check that the response is correct
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+
+	req, _ = http.NewRequest("GET", "/api/completed/pdf?summaryOnly=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+
+	req, _ = http.NewRequest("GET", "/api/completed/pdf?summaryOnly=maybe", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCompletedSARIF(t *testing.T) {
+	service := analysis.NewService(10)
+	a, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(a.ID, analysis.StatusCompleted, []analysis.Issue{
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error", Message: "Low contrast", Selector: "#nav > a", Context: "<a>Home</a>"},
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error", Message: "Low contrast", Selector: "footer p"},
+		{Code: "custom-check", Type: "notice", Message: "Check this"},
+	}, "")
+
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	req, _ := http.NewRequest("GET", "/api/completed/sarif?id="+a.ID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/sarif+json", w.Header().Get("Content-Type"))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID      string `json:"id"`
+						HelpURI string `json:"helpUri"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+					LogicalLocations []struct {
+						Name string `json:"name"`
+					} `json:"logicalLocations"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	run := log.Runs[0]
+	assert.Len(t, run.Tool.Driver.Rules, 2, "rules are shared by the issues with the same code")
+	assert.Equal(t, "https://www.w3.org/WAI/WCAG22/Understanding/contrast-minimum.html", run.Tool.Driver.Rules[0].HelpURI)
+	assert.Empty(t, run.Tool.Driver.Rules[1].HelpURI)
+	assert.Len(t, run.Results, 3)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, "note", run.Results[2].Level)
+	assert.Equal(t, 1, run.Results[2].RuleIndex)
+	assert.Equal(t, "http://example.com", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, "#nav > a", run.Results[0].Locations[0].LogicalLocations[0].Name)
+}
+
+func TestCompletedJUnit(t *testing.T) {
+	service := analysis.NewService(10)
+	a, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(a.ID, analysis.StatusCompleted, []analysis.Issue{
+		{Code: "image-alt", Type: "error", Message: "Images must have alternate text", Selector: "img.logo", Context: "<img class=\"logo\">"},
+		{Code: "image-alt", Type: "error", Message: "Images must have alternate text", Selector: "img.hero"},
+		{Code: "heading-order", Type: "warning", Message: "Heading levels should increase by one", Selector: "h4"},
+	}, "")
+	failed, err := service.Create(analysis.Request{URL: "http://example.org"})
+	assert.NoError(t, err)
+	service.UpdateResult(failed.ID, analysis.StatusFailed, nil, "URL not reachable")
+
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	req, _ := http.NewRequest("GET", "/api/completed/junit", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `<testsuites name="pa11y" tests="2" failures="1" errors="0">`)
+	assert.Contains(t, body, `<testsuite name="http://example.com" tests="2" failures="1" errors="0"`)
+	assert.Contains(t, body, `<failure message="2 errors: Images must have alternate text" type="error">`)
+	assert.Contains(t, body, "Selector: img.hero")
+	assert.Contains(t, body, "Context: &lt;img class=&#34;logo&#34;&gt;")
+	assert.NotContains(t, body, "example.org", "only completed analyses are exported by default")
+
+	req, _ = http.NewRequest("GET", "/api/completed/junit?id="+failed.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<error message="analysis failed" type="failed">URL not reachable</error>`)
+}
+
+func TestCompletedCSVAndXLSX(t *testing.T) {
+	service := analysis.NewService(10)
+	a, err := service.Create(analysis.Request{URL: "http://example.com", Runners: []string{"axe"}})
+	assert.NoError(t, err)
+	service.UpdateResult(a.ID, analysis.StatusCompleted, []analysis.Issue{
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error", TypeCode: 1, Message: "Low contrast, fix it", Selector: "p", Runner: "htmlcs"},
+		{Code: "image-alt", Type: "error", TypeCode: 1, Message: "Missing alt", Selector: "img", Context: "<img src=\"a.png\">"},
+		{Code: "WCAG2AA.Principle1.Guideline1_3.1_3_1.H48", Type: "warning", TypeCode: 2, Message: "List markup", Selector: "ul"},
+	}, "")
+
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	req, _ := http.NewRequest("GET", "/api/completed/csv?type=error", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, issueColumns, records[0])
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{a.ID, "http://example.com", "htmlcs", "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", "error", "1", "Low contrast, fix it", "p", "", "1.4.3"}, records[1])
+	assert.Equal(t, "axe", records[2][2], "issues without a runner fall back to the analysis runner")
+	assert.Equal(t, `<img src="a.png">`, records[2][8])
+
+	req, _ = http.NewRequest("GET", "/api/completed/csv?code=WCAG2AA.Principle1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	records, err = csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3, "codes match by dotted prefix")
+
+	req, _ = http.NewRequest("GET", "/api/completed/xlsx?type=warning,notice", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	f, err := excelize.OpenReader(w.Body)
+	assert.NoError(t, err)
+	rows, err := f.GetRows("Issues")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "WCAG2AA.Principle1.Guideline1_3.1_3_1.H48", rows[1][3])
+	assert.Equal(t, "2", rows[1][5])
+}
+
+func TestQueueFull(t *testing.T) {
+	service := analysis.NewService(1)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	body := `{"url": "http://example.com"}`
+	req, _ := http.NewRequest("POST", "/api/queue", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/queue", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestQueueInvalidOptions(t *testing.T) {
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	body := `{"url": "http://example.com", "options": {"standard": "Section508"}}`
+	req, _ := http.NewRequest("POST", "/api/queue", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, service.GetAll())
+}
+
+func TestDiffHTML(t *testing.T) {
+	service := analysis.NewService(10)
+	base, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(base.ID, analysis.StatusCompleted, []analysis.Issue{{Code: "resolved-code", Selector: "img"}}, "")
+	head, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(head.ID, analysis.StatusCompleted, []analysis.Issue{{Code: "new-code", Selector: "a"}}, "")
+
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	req, _ := http.NewRequest("GET", "/api/diff/html?base="+base.ID+"&head="+head.ID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "New issues (1)")
+	assert.Contains(t, w.Body.String(), "new-code")
+	assert.Contains(t, w.Body.String(), "Resolved issues (1)")
+
+	req, _ = http.NewRequest("GET", "/api/diff?base="+base.ID+"&head=missing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	other, err := service.Create(analysis.Request{URL: "http://example.org"})
+	assert.NoError(t, err)
+	service.UpdateResult(other.ID, analysis.StatusCompleted, nil, "")
+	req, _ = http.NewRequest("GET", "/api/diff?base="+base.ID+"&head="+other.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "http://example.org")
+}
+
+func TestAuthRedactedFromResponses(t *testing.T) {
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	body := `{"url": "http://example.com", "auth": {"headers": {"X-Api-Key": "s3cr3t"}, "basicAuth": {"username": "alice", "password": "s3cr3t"}}}`
+	req, _ := http.NewRequest("POST", "/api/queue", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cr3t")
+
+	a := service.GetAll()[0]
+	service.UpdateResult(a.ID, analysis.StatusCompleted, nil, "")
+	for _, path := range []string{"/api/queue", "/api/queue/" + a.ID, "/api/completed/html"} {
+		req, _ = http.NewRequest("GET", path, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "s3cr3t", path)
+	}
+	assert.Contains(t, w.Body.String(), "basic auth: alice")
+}
+
+func TestProfiles(t *testing.T) {
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	body := `{"name": "staging-login", "headers": {"Authorization": "Bearer s3cr3t"}}`
+	req, _ := http.NewRequest("POST", "/api/profiles", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "profiles need a server key")
+
+	assert.NoError(t, service.EnableProfiles("server-key"))
+	req, _ = http.NewRequest("POST", "/api/profiles", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cr3t")
+
+	req, _ = http.NewRequest("POST", "/api/queue", strings.NewReader(`{"url": "http://example.com", "profile": "staging-login"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"profile":"staging-login"`)
+
+	req, _ = http.NewRequest("GET", "/api/profiles/staging-login", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cr3t")
+
+	req, _ = http.NewRequest("DELETE", "/api/profiles/staging-login", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestWebhooks(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer receiver.Close()
+
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	handlers := NewHandlers(service, discoveryService, nil)
+	router := NewRouter(handlers, frontendAssets)
+
+	body := `{"url": "` + receiver.URL + `", "events": ["failed"], "secret": "s3cr3t"}`
+	req, _ := http.NewRequest("POST", "/api/webhooks", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	webhooks := webhook.NewService()
+	webhooks.Start(service.Events())
+	handlers.SetWebhooks(webhooks)
+	req, _ = http.NewRequest("POST", "/api/webhooks", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "s3cr3t")
+
+	req, _ = http.NewRequest("GET", "/api/webhooks", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cr3t")
+
+	a, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(a.ID, analysis.StatusFailed, nil, "URL not reachable")
+
+	select {
+	case r := <-received:
+		assert.Equal(t, webhook.EventFailed, r.Header.Get(webhook.EventHeader))
+		assert.Contains(t, r.Header.Get(webhook.SignatureHeader), "sha256=")
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+// fakePa11y points PA11Y_COMMAND at a shell script with the given body for the duration of the test.
+func fakePa11y(t *testing.T, body string) {
+	path := filepath.Join(t.TempDir(), "pa11y")
+	assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+	t.Setenv("PA11Y_COMMAND", path)
+}
+
+func TestAnalyzeSynchronously(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	}))
+	defer site.Close()
+	fakePa11y(t, `echo '[{"code":"WCAG2AA.Principle1.Guideline1_1.1_1_1.H37","type":"error","typeCode":1,"selector":"img","runner":"htmlcs"}]'; exit 2`)
+
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	req, _ := http.NewRequest("POST", "/api/analyze", strings.NewReader(`{"url": "`+site.URL+`"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"completed"`)
+	assert.Contains(t, w.Body.String(), "H37")
+	assert.Empty(t, service.GetAll(), "unrecorded analyses are not stored")
+
+	req, _ = http.NewRequest("POST", "/api/analyze", strings.NewReader(`{"url": "`+site.URL+`", "record": true}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	completed := service.GetCompleted()
+	if assert.Len(t, completed, 1) {
+		assert.Len(t, completed[0].Result, 1)
+	}
+}
+
+func TestAnalyzeTimeout(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer site.Close()
+	fakePa11y(t, "exec sleep 5")
+
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	handlers := NewHandlers(service, discoveryService, nil)
+	handlers.SetAnalyzeTimeout(200 * time.Millisecond)
+	router := NewRouter(handlers, frontendAssets)
+
+	req, _ := http.NewRequest("POST", "/api/analyze", strings.NewReader(`{"url": "`+site.URL+`", "record": true}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	all := service.GetAll()
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, analysis.StatusFailed, all[0].Status)
+		assert.Equal(t, analysis.ErrTimeout.Error(), all[0].ErrorMessage)
+	}
+}
+
+func TestQueueItemEvents(t *testing.T) {
+	service := analysis.NewService(10)
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	server := httptest.NewServer(NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets))
+	defer server.Close()
+
+	a, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+
+	resp, err := http.Get(server.URL + "/api/queue/" + a.ID + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	go func() {
+		// Give the handler time to send the current state before moving on.
+		time.Sleep(50 * time.Millisecond)
+		service.UpdateStatus(a.ID, analysis.StatusProcessing)
+		service.UpdateResult(a.ID, analysis.StatusCompleted, nil, "")
+	}()
+
+	// The stream ends by itself once the analysis completes.
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	events := strings.Count(string(body), "event:status")
+	assert.Equal(t, 3, events, string(body))
+	assert.Contains(t, string(body), `"status":"completed"`)
+
+	resp, err = http.Get(server.URL + "/api/queue/missing/events")
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.JSONEq(t, `{"error": "`+analysis.ErrNotFound.Error()+`"}`, string(body))
+	}
+}
+
+func TestConformance(t *testing.T) {
+	service := analysis.NewService(10)
+	home, err := service.Create(analysis.Request{URL: "http://example.com"})
+	assert.NoError(t, err)
+	service.UpdateResult(home.ID, analysis.StatusCompleted, []analysis.Issue{
+		{Code: "WCAG2AA.Principle1.Guideline1_4.1_4_3.G18.Fail", Type: "error", Message: "Low contrast"},
+	}, "")
+	about, err := service.Create(analysis.Request{URL: "http://example.com/about"})
+	assert.NoError(t, err)
+	service.UpdateResult(about.ID, analysis.StatusCompleted, nil, "")
+	pending, err := service.Create(analysis.Request{URL: "http://example.com/pending"})
+	assert.NoError(t, err)
+
+	discoveryService, err := discovery.NewService()
+	assert.NoError(t, err)
+	router := NewRouter(NewHandlers(service, discoveryService, nil), frontendAssets)
+
+	req, _ := http.NewRequest("GET", "/api/conformance?version=2.1&id="+home.ID+","+about.ID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report analysis.ConformanceReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "2.1", report.Version)
+	assert.Len(t, report.Pages, 2)
+	assert.Len(t, report.Criteria, 50)
+	assert.Equal(t, 1, report.Summary[analysis.ConformancePartiallySupports])
+
+	req, _ = http.NewRequest("GET", "/api/conformance/html", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<h1>Accessibility Conformance Report</h1>")
+	assert.Contains(t, w.Body.String(), "<h2>Table: Success Criteria, Level AA</h2>")
+	assert.Contains(t, w.Body.String(), "1.4.3 Contrast (Minimum)</a></td><td>Partially Supports</td><td>1 error on 1 of 2 pages</td>")
+
+	req, _ = http.NewRequest("GET", "/api/conformance/pdf?id="+home.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+
+	for query, status := range map[string]int{
+		"?version=3.0":      http.StatusBadRequest,
+		"?id=missing":       http.StatusNotFound,
+		"?id=" + pending.ID: http.StatusConflict,
+	} {
+		req, _ = http.NewRequest("GET", "/api/conformance"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, query)
+	}
+
+	// Only the latest run of a URL counts: the contrast error was fixed by the rerun.
+	rerun, err := service.Rerun(home.ID)
+	assert.NoError(t, err)
+	service.UpdateResult(rerun.ID, analysis.StatusCompleted, nil, "")
+	req, _ = http.NewRequest("GET", "/api/conformance", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	report = analysis.ConformanceReport{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	if assert.Len(t, report.Pages, 2) {
+		assert.Equal(t, []string{rerun.ID, about.ID}, []string{report.Pages[0].AnalysisID, report.Pages[1].AnalysisID})
+	}
+	assert.Equal(t, 0, report.Summary[analysis.ConformancePartiallySupports])
 }
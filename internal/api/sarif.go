@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"pa11y-go-wrapper/internal/analysis"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// The SARIF types cover the subset of the SARIF 2.1.0 object model the printer emits.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool      sarifTool       `json:"tool"`
+	Artifacts []sarifArtifact `json:"artifacts"`
+	Results   []sarifResult   `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name,omitempty"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	FullDescription  *sarifMessage   `json:"fullDescription,omitempty"`
+	HelpURI          string          `json:"helpUri,omitempty"`
+	Properties       *sarifRuleProps `json:"properties,omitempty"`
+}
+
+type sarifRuleProps struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifArtifact struct {
+	Location sarifArtifactLocation `json:"location"`
+}
+
+type sarifArtifactLocation struct {
+	URI   string `json:"uri"`
+	Index int    `json:"index"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	RuleIndex  int               `json:"ruleIndex"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// sarifLevels maps pa11y issue types to SARIF result levels.
+var sarifLevels = map[string]string{
+	"error":   "error",
+	"warning": "warning",
+	"notice":  "note",
+}
+
+// GenerateSARIF generates a SARIF 2.1.0 log from a list of analyses. Every issue of a completed
+// analysis becomes a result whose rule is the issue code, located in the analyzed page and, via
+// its selector, in the element that caused it.
+func GenerateSARIF(analyses []*analysis.Analysis) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "pa11y",
+			InformationURI: "https://pa11y.org/",
+			Rules:          []sarifRule{},
+		}},
+		Artifacts: []sarifArtifact{},
+		Results:   []sarifResult{},
+	}
+	ruleIndex := make(map[string]int)
+	artifactIndex := make(map[string]int)
+
+	for _, a := range analyses {
+		if a.Status != analysis.StatusCompleted {
+			continue
+		}
+		artifact, ok := artifactIndex[a.URL]
+		if !ok {
+			artifact = len(run.Artifacts)
+			artifactIndex[a.URL] = artifact
+			run.Artifacts = append(run.Artifacts, sarifArtifact{Location: sarifArtifactLocation{URI: a.URL, Index: artifact}})
+		}
+
+		for _, issue := range a.Result {
+			rule, ok := ruleIndex[issue.Code]
+			if !ok {
+				rule = len(run.Tool.Driver.Rules)
+				ruleIndex[issue.Code] = rule
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newSARIFRule(issue))
+			}
+
+			level, ok := sarifLevels[issue.Type]
+			if !ok {
+				level = "none"
+			}
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: a.URL, Index: artifact},
+			}}
+			if issue.Selector != "" {
+				location.LogicalLocations = []sarifLogicalLocation{{Name: issue.Selector, Kind: "element"}}
+			}
+			properties := map[string]string{"analysisId": a.ID}
+			if issue.Context != "" {
+				properties["context"] = issue.Context
+			}
+			if runners := issueRunners(issue); runners != "" {
+				properties["runner"] = runners
+			}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:     issue.Code,
+				RuleIndex:  rule,
+				Level:      level,
+				Message:    sarifMessage{Text: issue.Message},
+				Locations:  []sarifLocation{location},
+				Properties: properties,
+			})
+		}
+	}
+
+	return json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}, "", "  ")
+}
+
+// newSARIFRule describes the rule behind an issue code, linking to the WCAG success criterion
+// it relates to when known.
+func newSARIFRule(issue analysis.Issue) sarifRule {
+	rule := sarifRule{ID: issue.Code, ShortDescription: sarifMessage{Text: issue.Message}}
+	tags := []string{"accessibility"}
+	if criterion, ok := analysis.LookupCriterion(analysis.WCAGCriterion(issue)); ok {
+		rule.Name = criterion.Name
+		rule.FullDescription = &sarifMessage{Text: fmt.Sprintf("WCAG %s %s (Level %s)", criterion.Number, criterion.Name, criterion.Level)}
+		rule.HelpURI = criterion.URL()
+		tags = append(tags, "wcag"+criterion.Number, "level-"+criterion.Level)
+	}
+	rule.Properties = &sarifRuleProps{Tags: tags}
+	return rule
+}
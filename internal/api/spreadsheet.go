@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"pa11y-go-wrapper/internal/analysis"
+	"slices"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// issueColumns are the columns of the CSV and XLSX exports.
+var issueColumns = []string{
+	"Analysis ID", "URL", "Runner", "Code", "Type", "Type Code", "Message", "Selector", "Context", "WCAG Criterion",
+}
+
+// IssueFilter selects the issues included in an export. Empty fields select everything.
+type IssueFilter struct {
+	// Types lists the issue types to include, e.g. "error".
+	Types []string
+	// Codes lists the issue codes to include; a code also matches the codes it is a
+	// dot-separated prefix of.
+	Codes []string
+}
+
+// Matches reports whether issue passes the filter.
+func (f IssueFilter) Matches(issue analysis.Issue) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, issue.Type) {
+		return false
+	}
+	if len(f.Codes) > 0 && !slices.ContainsFunc(f.Codes, func(code string) bool {
+		return analysis.MatchesCode(issue.Code, code)
+	}) {
+		return false
+	}
+	return true
+}
+
+// issueRows flattens the issues of the analyses that pass filter into rows of issueColumns.
+func issueRows(analyses []*analysis.Analysis, filter IssueFilter) [][]string {
+	var rows [][]string
+	for _, a := range analyses {
+		for _, issue := range a.Result {
+			if !filter.Matches(issue) {
+				continue
+			}
+			runner := issueRunners(issue)
+			if runner == "" {
+				runner = analysisRunners(a)
+			}
+			rows = append(rows, []string{
+				a.ID,
+				a.URL,
+				runner,
+				issue.Code,
+				issue.Type,
+				strconv.Itoa(issue.TypeCode),
+				issue.Message,
+				issue.Selector,
+				issue.Context,
+				analysis.WCAGCriterion(issue),
+			})
+		}
+	}
+	return rows
+}
+
+// GenerateCSV generates a CSV file with one row per issue of the analyses that passes filter.
+func GenerateCSV(analyses []*analysis.Analysis, filter IssueFilter) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(issueColumns); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(issueRows(analyses, filter)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateXLSX generates an Excel workbook with one row per issue of the analyses that passes
+// filter, on a sheet with a frozen, filterable header row.
+func GenerateXLSX(analyses []*analysis.Analysis, filter IssueFilter) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Issues"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	rows := issueRows(analyses, filter)
+	if err := f.SetSheetRow(sheet, "A1", &issueColumns); err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(row))
+		for j, value := range row {
+			values[j] = value
+		}
+		// Keep the type code numeric so that it can be sorted and summed.
+		if typeCode, err := strconv.Atoi(row[5]); err == nil {
+			values[5] = typeCode
+		}
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	lastColumn, err := excelize.ColumnNumberToName(len(issueColumns))
+	if err != nil {
+		return nil, err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	if err := f.SetCellStyle(sheet, "A1", lastColumn+"1", bold); err != nil {
+		return nil, err
+	}
+	if err := f.AutoFilter(sheet, "A1:"+lastColumn+strconv.Itoa(len(rows)+1), nil); err != nil {
+		return nil, err
+	}
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+	for i, width := range []float64{38, 40, 12, 50, 10, 10, 70, 40, 60, 15} {
+		column, _ := excelize.ColumnNumberToName(i + 1)
+		if err := f.SetColWidth(sheet, column, column, width); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
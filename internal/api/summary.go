@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"pa11y-go-wrapper/internal/analysis"
+	"sort"
+
+	"github.com/johnfercher/maroto/v2/pkg/components/col"
+	mimage "github.com/johnfercher/maroto/v2/pkg/components/image"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+)
+
+// summaryTopCodes is how many rule codes the executive summary ranks.
+const summaryTopCodes = 10
+
+// Bar charts are drawn as images of barChartWidth by barChartHeight pixels, which keeps their
+// aspect ratio close to that of the table cell they are placed in.
+const (
+	barChartWidth  = 480
+	barChartHeight = 24
+)
+
+// reportSummary holds the figures of the executive summary of a report.
+type reportSummary struct {
+	URLs      int
+	Completed int
+	Counts    analysis.IssueCounts
+	// Score is the average Score of the completed analyses.
+	Score       int
+	GatesPassed int
+	GatesFailed int
+	TopCodes    []codeCount
+}
+
+// codeCount counts the occurrences of a rule code across a report.
+type codeCount struct {
+	Code string
+	// Type is the most severe type the code was reported with.
+	Type     string
+	typeCode int
+	Count    int
+	// Pages is the number of analyses the code was found in.
+	Pages int
+}
+
+// summarizeReport computes the executive summary of a list of analyses.
+func summarizeReport(analyses []*analysis.Analysis) reportSummary {
+	s := reportSummary{URLs: len(analyses)}
+	scores := 0
+	byCode := make(map[string]*codeCount)
+	for _, a := range analyses {
+		counts := analysis.CountIssues(a.Result)
+		s.Counts.Errors += counts.Errors
+		s.Counts.Warnings += counts.Warnings
+		s.Counts.Notices += counts.Notices
+		s.Counts.Total += counts.Total
+		if a.Status == analysis.StatusCompleted {
+			s.Completed++
+			scores += analysis.Score(a.Result)
+		}
+		switch a.Gate {
+		case analysis.GatePassed:
+			s.GatesPassed++
+		case analysis.GateFailed:
+			s.GatesFailed++
+		}
+
+		seen := make(map[string]bool)
+		for _, issue := range a.Result {
+			cc, ok := byCode[issue.Code]
+			if !ok {
+				cc = &codeCount{Code: issue.Code, Type: issue.Type, typeCode: issue.TypeCode}
+				byCode[issue.Code] = cc
+			}
+			if issue.TypeCode < cc.typeCode {
+				cc.Type, cc.typeCode = issue.Type, issue.TypeCode
+			}
+			cc.Count++
+			if !seen[issue.Code] {
+				seen[issue.Code] = true
+				cc.Pages++
+			}
+		}
+	}
+	if s.Completed > 0 {
+		s.Score = scores / s.Completed
+	}
+
+	for _, cc := range byCode {
+		s.TopCodes = append(s.TopCodes, *cc)
+	}
+	sort.Slice(s.TopCodes, func(i, j int) bool {
+		if s.TopCodes[i].Count != s.TopCodes[j].Count {
+			return s.TopCodes[i].Count > s.TopCodes[j].Count
+		}
+		return s.TopCodes[i].Code < s.TopCodes[j].Code
+	})
+	if len(s.TopCodes) > summaryTopCodes {
+		s.TopCodes = s.TopCodes[:summaryTopCodes]
+	}
+	return s
+}
+
+// getExecutiveSummaryRows renders the executive summary of a report: its totals and overall
+// score, a bar chart of the errors, warnings and notices of each URL and the most recurring
+// rule codes.
+func getExecutiveSummaryRows(analyses []*analysis.Analysis) ([]core.Row, error) {
+	s := summarizeReport(analyses)
+
+	rows := []core.Row{
+		text.NewRow(8, "Executive Summary", props.Text{Style: fontstyle.Bold, Align: align.Left}),
+	}
+
+	score := "n/a"
+	if s.Completed > 0 {
+		score = fmt.Sprintf("%d / 100", s.Score)
+	}
+	totals := [][2]string{
+		{"URLs:", fmt.Sprintf("%d (%d completed)", s.URLs, s.Completed)},
+		{"Errors:", fmt.Sprintf("%d", s.Counts.Errors)},
+		{"Warnings:", fmt.Sprintf("%d", s.Counts.Warnings)},
+		{"Notices:", fmt.Sprintf("%d", s.Counts.Notices)},
+		{"Overall Score:", score},
+	}
+	if s.GatesPassed+s.GatesFailed > 0 {
+		totals = append(totals, [2]string{"Quality Gates:", fmt.Sprintf("%d passed, %d failed", s.GatesPassed, s.GatesFailed)})
+	}
+	for _, kv := range totals {
+		rows = append(rows, row.New(5).Add(
+			text.NewCol(3, kv[0], props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left}),
+			text.NewCol(9, kv[1], props.Text{Size: 9, Align: align.Left}),
+		))
+	}
+	if s.Completed > 0 {
+		rows = append(rows, text.NewRow(5, "Pages start at 100 points and lose 5 per error and 1 per warning; the overall score averages the completed pages.", props.Text{Size: 7, Top: 1, Align: align.Left}))
+	}
+
+	// Issues per URL
+	if len(analyses) > 0 {
+		rows = append(rows,
+			text.NewRow(4, " ", props.Text{}),
+			text.NewRow(7, "Issues per URL", props.Text{Style: fontstyle.Bold, Align: align.Left}),
+			row.New(5).Add(
+				col.New(1).WithStyle(&props.Cell{BackgroundColor: getErrorColor()}),
+				text.NewCol(2, "Errors", props.Text{Size: 8, Left: 2, Align: align.Left}),
+				col.New(1).WithStyle(&props.Cell{BackgroundColor: getWarningColor()}),
+				text.NewCol(2, "Warnings", props.Text{Size: 8, Left: 2, Align: align.Left}),
+				col.New(1).WithStyle(&props.Cell{BackgroundColor: getNoticeColor()}),
+				text.NewCol(5, "Notices", props.Text{Size: 8, Left: 2, Align: align.Left}),
+			),
+		)
+	}
+	maxTotal := 0
+	for _, a := range analyses {
+		maxTotal = max(maxTotal, len(a.Result))
+	}
+	for _, a := range analyses {
+		counts := analysis.CountIssues(a.Result)
+		chart, err := barChartPNG([]barSegment{
+			{Value: counts.Errors, Color: getErrorColor()},
+			{Value: counts.Warnings, Color: getWarningColor()},
+			{Value: counts.Notices, Color: getNoticeColor()},
+		}, maxTotal)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row.New(6).Add(
+			text.NewCol(4, a.URL, props.Text{Size: 8, Top: 1, Align: align.Left}),
+			mimage.NewFromBytesCol(6, chart, extension.Png, props.Rect{Top: 1, Percent: 100}),
+			text.NewCol(2, fmt.Sprintf("%d / %d / %d", counts.Errors, counts.Warnings, counts.Notices), props.Text{Size: 8, Top: 1, Align: align.Right}),
+		))
+	}
+
+	// Most recurring rule codes
+	if len(s.TopCodes) == 0 {
+		return rows, nil
+	}
+	rows = append(rows,
+		text.NewRow(4, " ", props.Text{}),
+		text.NewRow(7, fmt.Sprintf("Top %d Recurring Rule Codes", len(s.TopCodes)), props.Text{Style: fontstyle.Bold, Align: align.Left}),
+		row.New(5).Add(
+			text.NewCol(5, "Code", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Type", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Pages", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			text.NewCol(1, "Count", props.Text{Size: 9, Align: align.Center, Style: fontstyle.Bold}),
+			col.New(4),
+		),
+	)
+	for i, cc := range s.TopCodes {
+		chart, err := barChartPNG([]barSegment{{Value: cc.Count, Color: getTypeColor(cc.Type)}}, s.TopCodes[0].Count)
+		if err != nil {
+			return nil, err
+		}
+		r := row.New(6).Add(
+			text.NewCol(5, cc.Code, props.Text{Size: 8, Top: 1, Align: align.Left}),
+			text.NewCol(1, cc.Type, props.Text{Size: 8, Top: 1, Align: align.Center}),
+			text.NewCol(1, fmt.Sprintf("%d", cc.Pages), props.Text{Size: 8, Top: 1, Align: align.Center}),
+			text.NewCol(1, fmt.Sprintf("%d", cc.Count), props.Text{Size: 8, Top: 1, Align: align.Center}),
+			mimage.NewFromBytesCol(4, chart, extension.Png, props.Rect{Top: 1, Percent: 100}),
+		)
+		if i%2 == 0 {
+			r.WithStyle(&props.Cell{BackgroundColor: getGrayColor()})
+		}
+		rows = append(rows, r)
+	}
+
+	return rows, nil
+}
+
+// barSegment is one part of a stacked bar.
+type barSegment struct {
+	Value int
+	Color *props.Color
+}
+
+// barChartPNG draws a horizontal bar stacking segments from left to right, where scale is the
+// value that fills the whole width. Segments with a value are at least one pixel wide, so that
+// they stay visible next to much larger ones.
+func barChartPNG(segments []barSegment, scale int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, barChartWidth, barChartHeight))
+	x := 0
+	for _, seg := range segments {
+		if seg.Value <= 0 || scale <= 0 {
+			continue
+		}
+		width := max(seg.Value*barChartWidth/scale, 1)
+		end := min(x+width, barChartWidth)
+		c := color.RGBA{R: uint8(seg.Color.Red), G: uint8(seg.Color.Green), B: uint8(seg.Color.Blue), A: 255}
+		draw.Draw(img, image.Rect(x, 0, end, barChartHeight), &image.Uniform{C: c}, image.Point{}, draw.Src)
+		x = end
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// getTypeColor returns the chart color of an issue type.
+func getTypeColor(issueType string) *props.Color {
+	switch issueType {
+	case "error":
+		return getErrorColor()
+	case "warning":
+		return getWarningColor()
+	default:
+		return getNoticeColor()
+	}
+}
+
+func getErrorColor() *props.Color {
+	return &props.Color{Red: 211, Green: 47, Blue: 47}
+}
+
+func getWarningColor() *props.Color {
+	return &props.Color{Red: 245, Green: 124, Blue: 0}
+}
+
+func getNoticeColor() *props.Color {
+	return &props.Color{Red: 25, Green: 118, Blue: 210}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"pa11y-go-wrapper/internal/analysis"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeReport(t *testing.T) {
+	analyses := []*analysis.Analysis{
+		{URL: "https://example.com/", Status: analysis.StatusCompleted, Gate: analysis.GatePassed, Result: []analysis.Issue{
+			{Code: "contrast", Type: "warning", TypeCode: 2},
+			{Code: "contrast", Type: "error", TypeCode: 1},
+			{Code: "alt", Type: "error", TypeCode: 1},
+		}},
+		{URL: "https://example.com/about", Status: analysis.StatusCompleted, Gate: analysis.GateFailed, Result: []analysis.Issue{
+			{Code: "contrast", Type: "warning", TypeCode: 2},
+			{Code: "landmark", Type: "notice", TypeCode: 3},
+		}},
+		{URL: "https://example.com/broken", Status: analysis.StatusFailed},
+	}
+	for i := range 12 {
+		analyses[1].Result = append(analyses[1].Result, analysis.Issue{Code: fmt.Sprintf("rare-%02d", i), Type: "notice", TypeCode: 3})
+	}
+
+	s := summarizeReport(analyses)
+	assert.Equal(t, 3, s.URLs)
+	assert.Equal(t, 2, s.Completed)
+	assert.Equal(t, analysis.IssueCounts{Errors: 2, Warnings: 2, Notices: 13, Total: 17}, s.Counts)
+	assert.Equal(t, (89+99)/2, s.Score)
+	assert.Equal(t, 1, s.GatesPassed)
+	assert.Equal(t, 1, s.GatesFailed)
+
+	assert.Len(t, s.TopCodes, summaryTopCodes)
+	assert.Equal(t, codeCount{Code: "contrast", Type: "error", typeCode: 1, Count: 3, Pages: 2}, s.TopCodes[0])
+	assert.Equal(t, "alt", s.TopCodes[1].Code)
+	assert.Equal(t, "landmark", s.TopCodes[2].Code)
+	assert.Equal(t, "rare-06", s.TopCodes[9].Code, "ties are ranked by code")
+}
+
+func TestBarChartPNG(t *testing.T) {
+	chart, err := barChartPNG([]barSegment{
+		{Value: 2, Color: getErrorColor()},
+		{Value: 0, Color: getWarningColor()},
+		{Value: 1, Color: getNoticeColor()},
+	}, 4)
+	assert.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(chart))
+	assert.NoError(t, err)
+	assert.Equal(t, barChartWidth, img.Bounds().Dx())
+	assert.Equal(t, barChartHeight, img.Bounds().Dy())
+
+	colorAt := func(x int) [4]uint32 {
+		r, g, b, a := img.At(x, barChartHeight/2).RGBA()
+		return [4]uint32{r >> 8, g >> 8, b >> 8, a >> 8}
+	}
+	assert.Equal(t, [4]uint32{211, 47, 47, 255}, colorAt(0))
+	assert.Equal(t, [4]uint32{211, 47, 47, 255}, colorAt(barChartWidth/2-1))
+	assert.Equal(t, [4]uint32{25, 118, 210, 255}, colorAt(barChartWidth/2))
+	assert.Equal(t, [4]uint32{0, 0, 0, 0}, colorAt(barChartWidth*3/4), "the rest of the scale is left empty")
+}
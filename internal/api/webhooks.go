@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"pa11y-go-wrapper/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetWebhooks enables the webhook endpoints, backed by service.
+func (h *Handlers) SetWebhooks(service *webhook.Service) {
+	h.webhookService = service
+}
+
+// webhooks returns the webhook service, responding with 503 if webhooks are disabled.
+func (h *Handlers) webhooks(c *gin.Context) (*webhook.Service, bool) {
+	if h.webhookService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhooks are disabled"})
+		return nil, false
+	}
+	return h.webhookService, true
+}
+
+// respondWebhookError writes the HTTP response matching a webhook service error.
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateWebhook subscribes a URL to analysis events. The response is the only one that
+// includes the signing secret.
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	webhooks, ok := h.webhooks(c)
+	if !ok {
+		return
+	}
+	var req webhook.Webhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := webhooks.Create(req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetWebhooks returns all webhooks without their secrets.
+func (h *Handlers) GetWebhooks(c *gin.Context) {
+	webhooks, ok := h.webhooks(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, webhooks.GetAll())
+}
+
+// GetWebhook returns a webhook without its secret.
+func (h *Handlers) GetWebhook(c *gin.Context) {
+	webhooks, ok := h.webhooks(c)
+	if !ok {
+		return
+	}
+	w, ok := webhooks.GetByID(c.Param("id"))
+	if !ok {
+		respondWebhookError(c, webhook.ErrNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// DeleteWebhook removes a webhook.
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	webhooks, ok := h.webhooks(c)
+	if !ok {
+		return
+	}
+	if err := webhooks.Delete(c.Param("id")); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries returns the most recent deliveries of a webhook with their attempts.
+func (h *Handlers) GetWebhookDeliveries(c *gin.Context) {
+	webhooks, ok := h.webhooks(c)
+	if !ok {
+		return
+	}
+	deliveries, err := webhooks.Deliveries(c.Param("id"))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,299 @@
+package discovery
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Crawl limits used when none are configured.
+const (
+	DefaultCrawlDepth = 2
+	DefaultCrawlPages = 100
+	DefaultCrawlDelay = 100 * time.Millisecond
+)
+
+// maxCrawlBodySize is how much of a page the crawler reads to find its links.
+const maxCrawlBodySize = 2 << 20
+
+// trackingParams are query parameters that only track where visitors come from. They are
+// dropped so that links to the same page are crawled once.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_gl":     true,
+	"igshid":  true,
+	"yclid":   true,
+}
+
+// skippedExtensions are the file extensions of links that do not lead to web pages.
+var skippedExtensions = map[string]bool{
+	".7z": true, ".avi": true, ".css": true, ".csv": true, ".doc": true, ".docx": true,
+	".exe": true, ".gif": true, ".gz": true, ".ico": true, ".jpeg": true, ".jpg": true,
+	".js": true, ".json": true, ".mov": true, ".mp3": true, ".mp4": true, ".pdf": true,
+	".png": true, ".ppt": true, ".pptx": true, ".rar": true, ".rss": true, ".svg": true,
+	".tar": true, ".txt": true, ".webm": true, ".webp": true, ".woff": true, ".woff2": true,
+	".xls": true, ".xlsx": true, ".xml": true, ".zip": true,
+}
+
+// CrawlOptions bounds a crawl. Zero values use the defaults.
+type CrawlOptions struct {
+	// MaxDepth is how many links away from the start page the crawler goes.
+	MaxDepth int
+	// MaxPages is how many pages the crawler fetches.
+	MaxPages int
+	// Delay is the pause between two requests. A negative delay disables it.
+	Delay time.Duration
+}
+
+// withDefaults fills in the unset limits.
+func (o CrawlOptions) withDefaults() CrawlOptions {
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = DefaultCrawlDepth
+	}
+	if o.MaxPages <= 0 {
+		o.MaxPages = DefaultCrawlPages
+	}
+	if o.Delay < 0 {
+		o.Delay = 0
+	} else if o.Delay == 0 {
+		o.Delay = DefaultCrawlDelay
+	}
+	return o
+}
+
+// Crawler discovers the pages of a site without a sitemap by following its links breadth first.
+type Crawler struct {
+	client  *http.Client
+	options CrawlOptions
+	// robots is nil when the crawler ignores robots.txt.
+	robots *Robots
+}
+
+// NewCrawler creates a new crawler bounded by options.
+func NewCrawler(options CrawlOptions) *Crawler {
+	return &Crawler{
+		client:  &http.Client{Timeout: 15 * time.Second},
+		options: options.withDefaults(),
+	}
+}
+
+// SetRobots makes the crawler skip the URLs disallowed by robots and wait at least its
+// Crawl-delay between two requests.
+func (c *Crawler) SetRobots(robots *Robots) {
+	c.robots = robots
+}
+
+// crawlItem is a page waiting to be fetched, with the number of links followed to reach it.
+type crawlItem struct {
+	url   string
+	depth int
+}
+
+// Crawl fetches startURL and the pages it links to, breadth first, and returns the URLs of the
+// HTML pages it found in the order it fetched them. Only links to the origin of startURL are
+// followed, except those marked rel="nofollow", those of pages whose robots meta tag says
+// "nofollow" and those disallowed by robots.txt. URLs are normalized, without fragments or
+// tracking parameters.
+func (c *Crawler) Crawl(startURL string) ([]string, error) {
+	start, err := url.Parse(startURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, fmt.Errorf("invalid start URL %q", startURL)
+	}
+	startNormalized, _ := normalizeURL(start, start)
+	origin, _ := url.Parse(startNormalized)
+	delay := c.options.Delay
+	if c.robots != nil {
+		delay = max(delay, c.robots.CrawlDelay)
+	}
+
+	var pages []string
+	var queue []crawlItem
+	if c.robots.Allowed(startNormalized) {
+		queue = append(queue, crawlItem{url: startNormalized})
+	}
+	seen := map[string]bool{startNormalized: true}
+	fetched := 0
+	for len(queue) > 0 && fetched < c.options.MaxPages {
+		item := queue[0]
+		queue = queue[1:]
+
+		if fetched > 0 {
+			time.Sleep(delay)
+		}
+		fetched++
+		pageURL, links, err := c.fetchLinks(item.url)
+		if err != nil {
+			fmt.Printf("failed to crawl %s: %v\n", item.url, err)
+			continue
+		}
+		if pageURL == nil {
+			continue
+		}
+		if pageURL.String() != item.url {
+			// The page redirected; keep the final URL if it stays on the site.
+			normalized, ok := normalizeURL(origin, pageURL)
+			if !ok || (normalized != item.url && seen[normalized]) || !c.robots.Allowed(normalized) {
+				continue
+			}
+			seen[normalized] = true
+			item.url = normalized
+		}
+		pages = append(pages, item.url)
+
+		if item.depth >= c.options.MaxDepth {
+			continue
+		}
+		for _, link := range links {
+			normalized, ok := normalizeURL(origin, link)
+			if !ok || seen[normalized] || !c.robots.Allowed(normalized) {
+				continue
+			}
+			seen[normalized] = true
+			queue = append(queue, crawlItem{url: normalized, depth: item.depth + 1})
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found by crawling %s", startURL)
+	}
+	return pages, nil
+}
+
+// fetchLinks fetches a page and returns its final URL with the absolute URLs of the links to
+// follow. The URL is nil when the response is not an HTML page.
+func (c *Crawler) fetchLinks(pageURL string) (*url.URL, []*url.URL, error) {
+	resp, err := get(c.client, pageURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, nil, nil
+	}
+
+	links, err := extractLinks(resp.Request.URL, io.LimitReader(resp.Body, maxCrawlBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Request.URL, links, nil
+}
+
+// extractLinks parses an HTML page and returns the absolute URLs of its <a href> links, resolved
+// against its <base href> if it has one. Links marked rel="nofollow" are left out, and so is
+// every link of a page whose robots meta tag says "nofollow".
+func extractLinks(pageURL *url.URL, body io.Reader) ([]*url.URL, error) {
+	base := pageURL
+	var links []*url.URL
+	z := html.NewTokenizer(body)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return links, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			switch token.Data {
+			case "base":
+				if href, ok := attr(token, "href"); ok {
+					if u, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+						base = u
+					}
+				}
+			case "meta":
+				name, _ := attr(token, "name")
+				content, _ := attr(token, "content")
+				if strings.EqualFold(name, "robots") && hasToken(content, ",", "nofollow") {
+					return nil, nil
+				}
+			case "a":
+				href, ok := attr(token, "href")
+				if !ok {
+					continue
+				}
+				if rel, _ := attr(token, "rel"); hasToken(rel, " ", "nofollow") {
+					continue
+				}
+				if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+					links = append(links, u)
+				}
+			}
+		}
+	}
+}
+
+// attr returns the value of an attribute of an HTML token.
+func attr(token html.Token, name string) (string, bool) {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// hasToken reports whether a list of tokens separated by sep contains token, ignoring case.
+func hasToken(list, sep, token string) bool {
+	for _, t := range strings.Split(list, sep) {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeURL returns the canonical form of a link if it belongs to the origin of site and
+// leads to a web page: lowercase scheme and host, no default port, no fragment, no tracking
+// parameters and sorted query parameters.
+func normalizeURL(site, link *url.URL) (string, bool) {
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return "", false
+	}
+	u := *link
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Scheme != site.Scheme || u.Host != strings.ToLower(site.Host) {
+		return "", false
+	}
+	if skippedExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return "", false
+	}
+
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	query := u.Query()
+	for name := range query {
+		if trackingParams[strings.ToLower(name)] || strings.HasPrefix(strings.ToLower(name), "utm_") {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u.String(), true
+}
@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLinkedSite serves HTML pages with the given bodies, where "{{site}}" stands for the URL of
+// the server. It has no sitemap.
+func newLinkedSite(t *testing.T, pages map[string]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><head><title>Page %s</title></head><body>%s</body></html>",
+			r.URL.Path, strings.ReplaceAll(body, "{{site}}", server.URL))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCrawl(t *testing.T) {
+	site := newLinkedSite(t, map[string]string{
+		"/": `<a href="/about">About</a>
+			<a href="blog#top">Blog</a>
+			<a href="{{site}}/about?utm_source=news&gclid=1">About again</a>
+			<a href="/login" rel="external nofollow">Log in</a>
+			<a href="https://example.com/elsewhere">Elsewhere</a>
+			<a href="mailto:info@example.com">Mail</a>
+			<a href="/brochure.pdf">Brochure</a>`,
+		"/about":           `<a href="/team">Team</a>`,
+		"/blog":            `<a href="/blog/first-post?page=1&b=2">First post</a><a href="/missing">Missing</a>`,
+		"/team":            `<a href="/deep">Too deep</a>`,
+		"/blog/first-post": `<meta name="robots" content="noindex, nofollow"><a href="/hidden">Hidden</a>`,
+		"/login":           ``,
+		"/deep":            ``,
+		"/hidden":          ``,
+	})
+
+	pages, err := NewCrawler(CrawlOptions{MaxDepth: 2, Delay: -1}).Crawl(site.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		site.URL + "/",
+		site.URL + "/about",
+		site.URL + "/blog",
+		site.URL + "/team",
+		site.URL + "/blog/first-post?b=2&page=1",
+	}, pages)
+}
+
+func TestCrawlPageLimit(t *testing.T) {
+	site := newLinkedSite(t, map[string]string{
+		"/":  `<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a>`,
+		"/a": ``,
+		"/b": ``,
+		"/c": ``,
+	})
+
+	pages, err := NewCrawler(CrawlOptions{MaxPages: 3, Delay: -1}).Crawl(site.URL + "/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{site.URL + "/", site.URL + "/a", site.URL + "/b"}, pages)
+}
+
+func TestCrawlWithoutPages(t *testing.T) {
+	site := newLinkedSite(t, map[string]string{})
+
+	_, err := NewCrawler(CrawlOptions{Delay: -1}).Crawl(site.URL)
+	assert.Error(t, err)
+}
+
+func TestNormalizeURL(t *testing.T) {
+	site, _ := url.Parse("https://example.com")
+	tests := []struct {
+		link string
+		want string
+		ok   bool
+	}{
+		{"https://EXAMPLE.com:443", "https://example.com/", true},
+		{"https://example.com/a?utm_campaign=x&id=2&fbclid=y#section", "https://example.com/a?id=2", true},
+		{"https://example.com/a?z=1&a=2", "https://example.com/a?a=2&z=1", true},
+		{"https://example.com/a?", "https://example.com/a", true},
+		{"http://example.com/a", "", false},
+		{"https://www.example.com/a", "", false},
+		{"https://example.com/logo.PNG", "", false},
+		{"javascript:void(0)", "", false},
+	}
+	for _, tt := range tests {
+		link, err := url.Parse(tt.link)
+		assert.NoError(t, err, tt.link)
+		got, ok := normalizeURL(site, link)
+		assert.Equal(t, tt.ok, ok, tt.link)
+		assert.Equal(t, tt.want, got, tt.link)
+	}
+}
+
+func TestDiscoverCrawlsWithoutSitemap(t *testing.T) {
+	site := newLinkedSite(t, map[string]string{
+		"/":        `<a href="/contact">Contact</a>`,
+		"/contact": ``,
+	})
+
+	s := NewServiceWithSelector(nil)
+	s.SetCrawlOptions(CrawlOptions{Delay: -1})
+	results, err := s.Discover(site.URL, "blog")
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{URL: site.URL + "/", Status: "200 OK", Category: "Homepage"},
+		{URL: site.URL + "/contact", Status: "200 OK", Category: "Contact"},
+	}, results)
+}
@@ -0,0 +1,271 @@
+package discovery
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// narrowedURLCount and selectedURLCount are how many URLs the two selection steps of a discovery
+// keep, matching what the LLM is asked for.
+const (
+	narrowedURLCount = 20
+	selectedURLCount = 10
+)
+
+// siblingThreshold is how many distinct segments must share a parent path before they are
+// considered values of one template, e.g. "/products/{slug}".
+const siblingThreshold = 3
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexIDPattern = regexp.MustCompile(`^[0-9a-fA-F]*[0-9][0-9a-fA-F]*$`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// categoryKeywords maps the words found in paths and titles to page categories, most specific
+// first.
+var categoryKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{"Checkout", []string{"cart", "basket", "checkout", "bag"}},
+	{"Account", []string{"login", "signin", "sign-in", "register", "signup", "sign-up", "account", "profile"}},
+	{"Search", []string{"search", "results"}},
+	{"Contact", []string{"contact", "contacts", "contact-us"}},
+	{"Product", []string{"product", "products", "item", "items", "shop", "store"}},
+	{"Category", []string{"category", "categories", "collection", "collections", "catalog", "catalogue"}},
+	{"Blog", []string{"blog", "blogs", "news", "article", "articles", "post", "posts", "stories", "magazine"}},
+	{"Documentation", []string{"docs", "documentation", "guide", "guides", "manual", "reference"}},
+	{"Help", []string{"help", "faq", "faqs", "support"}},
+	{"Events", []string{"event", "events", "agenda"}},
+	{"Careers", []string{"careers", "career", "jobs", "job"}},
+	{"Legal", []string{"legal", "privacy", "terms", "cookies", "cookie-policy", "imprint", "accessibility"}},
+	{"About", []string{"about", "about-us", "team", "company", "history", "mission"}},
+}
+
+// narrowDownURLsHeuristically picks up to limit URLs that cover as many kinds of pages as
+// possible without an LLM: URLs are clustered by path template, e.g. "/blog/{slug}", and one
+// representative is taken from each cluster in turn, largest clusters first.
+func narrowDownURLsHeuristically(urls []string, limit int) []string {
+	templates := pathTemplates(urls)
+
+	clusters := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		clusters[templates[u]] = append(clusters[templates[u]], u)
+	}
+
+	keys := make([]string, 0, len(clusters))
+	for key, members := range clusters {
+		keys = append(keys, key)
+		sort.Slice(members, func(i, j int) bool {
+			if len(members[i]) != len(members[j]) {
+				return len(members[i]) < len(members[j])
+			}
+			return members[i] < members[j]
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if (a == "/") != (b == "/") {
+			return a == "/"
+		}
+		if len(clusters[a]) != len(clusters[b]) {
+			return len(clusters[a]) > len(clusters[b])
+		}
+		if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+			return da < db
+		}
+		return a < b
+	})
+
+	var selected []string
+	for round := 0; len(selected) < limit; round++ {
+		picked := false
+		for _, key := range keys {
+			if round < len(clusters[key]) && len(selected) < limit {
+				selected = append(selected, clusters[key][round])
+				picked = true
+			}
+		}
+		if !picked {
+			break
+		}
+	}
+	return selected
+}
+
+// selectAndCategorizeURLsHeuristically picks up to limit URLs like narrowDownURLsHeuristically
+// and assigns each a category inferred from its path and the <title> in its head section.
+func selectAndCategorizeURLsHeuristically(urls []string, heads map[string]string, limit int) []Result {
+	selected := narrowDownURLsHeuristically(urls, limit)
+	results := make([]Result, 0, len(selected))
+	for _, u := range selected {
+		results = append(results, Result{URL: u, Category: inferCategory(u, heads[u])})
+	}
+	return results
+}
+
+// pathTemplates maps each URL to the template of its path, where segments that look like
+// identifiers become "{id}" and segments that vary between many siblings, or look like slugs,
+// become "{slug}". The site root is "/".
+func pathTemplates(urls []string) map[string]string {
+	segments := make([][]string, len(urls))
+	templates := make([][]string, len(urls))
+	depth := 0
+	for i, u := range urls {
+		segments[i] = pathSegments(u)
+		templates[i] = append([]string(nil), segments[i]...)
+		depth = max(depth, len(segments[i]))
+	}
+
+	// Templates are built one level at a time, so that siblings are counted under the template
+	// of their parent: "/blog/{slug}/comments" has the same parent for every post.
+	for d := range depth {
+		siblings := make(map[string]map[string]bool)
+		for i, segs := range segments {
+			if len(segs) <= d {
+				continue
+			}
+			parent := strings.Join(templates[i][:d], "/")
+			if siblings[parent] == nil {
+				siblings[parent] = make(map[string]bool)
+			}
+			siblings[parent][segs[d]] = true
+		}
+		for i, segs := range segments {
+			if len(segs) <= d {
+				continue
+			}
+			parent := strings.Join(templates[i][:d], "/")
+			switch {
+			case isIDSegment(segs[d]):
+				templates[i][d] = "{id}"
+			case isSlugSegment(segs[d]) || (d > 0 && len(siblings[parent]) >= siblingThreshold):
+				templates[i][d] = "{slug}"
+			}
+		}
+	}
+
+	result := make(map[string]string, len(urls))
+	for i, u := range urls {
+		result[u] = "/" + strings.Join(templates[i], "/")
+	}
+	return result
+}
+
+// pathSegments splits the path of a URL into its non-empty segments.
+func pathSegments(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// isIDSegment reports whether a path segment looks like a generated identifier: a number, a
+// UUID or a long hexadecimal string.
+func isIDSegment(segment string) bool {
+	segment = strings.TrimSuffix(segment, extension(segment))
+	if segment == "" {
+		return false
+	}
+	if strings.IndexFunc(segment, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+		return true
+	}
+	return uuidPattern.MatchString(segment) || (len(segment) >= 12 && hexIDPattern.MatchString(segment))
+}
+
+// isSlugSegment reports whether a path segment looks like the slug of a piece of content, such
+// as "how-to-choose-a-bike", rather than the name of a section.
+func isSlugSegment(segment string) bool {
+	return len(segmentWords(segment)) >= 3
+}
+
+// segmentWords splits a path segment into lowercase words, dropping its file extension.
+func segmentWords(segment string) []string {
+	segment = strings.ToLower(strings.TrimSuffix(segment, extension(segment)))
+	return strings.FieldsFunc(segment, func(r rune) bool {
+		return r == '-' || r == '_' || r == '+' || r == '.'
+	})
+}
+
+// extension returns the file extension of a path segment, such as ".html", if it has one.
+func extension(segment string) string {
+	if i := strings.LastIndex(segment, "."); i > 0 && len(segment)-i <= 5 {
+		return segment[i:]
+	}
+	return ""
+}
+
+// inferCategory guesses the category of a page from the sections of its path and, failing
+// that, from its title. Pages that match no known category are named after their first
+// section.
+func inferCategory(rawURL, head string) string {
+	segments := pathSegments(rawURL)
+	if len(segments) == 0 {
+		return "Homepage"
+	}
+
+	for _, segment := range segments {
+		if category := keywordCategory(append([]string{strings.ToLower(segment)}, segmentWords(segment)...)); category != "" {
+			return category
+		}
+	}
+	if title := pageTitle(head); title != "" {
+		words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+		})
+		if category := keywordCategory(words); category != "" {
+			return category
+		}
+	}
+
+	if isIDSegment(segments[0]) {
+		return "Page"
+	}
+	name := strings.Join(segmentWords(segments[0]), " ")
+	if name == "" {
+		return "Page"
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// keywordCategory returns the first category of categoryKeywords with a keyword among words.
+func keywordCategory(words []string) string {
+	for _, c := range categoryKeywords {
+		for _, keyword := range c.keywords {
+			for _, word := range words {
+				if word == keyword {
+					return c.category
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// pageTitle returns the text of the <title> element of a head section.
+func pageTitle(head string) string {
+	m := titlePattern.FindStringSubmatch(head)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(m[1])), " ")
+}
@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathTemplates(t *testing.T) {
+	urls := []string{
+		"https://example.com/",
+		"https://example.com/about",
+		"https://example.com/contact",
+		"https://example.com/blog",
+		"https://example.com/blog/hello",
+		"https://example.com/blog/spring-sale",
+		"https://example.com/blog/release-notes",
+		"https://example.com/help/how-to-reset-a-password",
+		"https://example.com/orders/12345",
+		"https://example.com/files/3f2504e0-4f89-11d3-9a0c-0305e82c3301.pdf",
+		"https://example.com/docs/intro",
+		"https://example.com/docs/install",
+	}
+
+	templates := pathTemplates(urls)
+	assert.Equal(t, "/", templates["https://example.com/"])
+	assert.Equal(t, "/about", templates["https://example.com/about"])
+	assert.Equal(t, "/blog", templates["https://example.com/blog"])
+	assert.Equal(t, "/blog/{slug}", templates["https://example.com/blog/hello"], "siblings make a template")
+	assert.Equal(t, "/blog/{slug}", templates["https://example.com/blog/release-notes"])
+	assert.Equal(t, "/help/{slug}", templates["https://example.com/help/how-to-reset-a-password"], "long slugs make a template on their own")
+	assert.Equal(t, "/orders/{id}", templates["https://example.com/orders/12345"])
+	assert.Equal(t, "/files/{id}", templates["https://example.com/files/3f2504e0-4f89-11d3-9a0c-0305e82c3301.pdf"])
+	assert.Equal(t, "/docs/intro", templates["https://example.com/docs/intro"], "two siblings stay distinct pages")
+}
+
+func TestNarrowDownURLsHeuristically(t *testing.T) {
+	urls := []string{"https://example.com/about", "https://example.com/contact"}
+	for i := range 30 {
+		urls = append(urls, fmt.Sprintf("https://example.com/products/item-%02d", i))
+	}
+	for i := range 10 {
+		urls = append(urls, fmt.Sprintf("https://example.com/blog/%d", 2000+i))
+	}
+	urls = append(urls, "https://example.com/", "https://example.com/about")
+
+	selected := narrowDownURLsHeuristically(urls, 6)
+	assert.Equal(t, []string{
+		"https://example.com/",
+		"https://example.com/products/item-00",
+		"https://example.com/blog/2000",
+		"https://example.com/about",
+		"https://example.com/contact",
+		"https://example.com/products/item-01",
+	}, selected, "the homepage comes first, then one URL per cluster, largest clusters first")
+
+	assert.Equal(t, selected, narrowDownURLsHeuristically(urls, 6), "the selection is deterministic")
+	assert.Len(t, narrowDownURLsHeuristically(urls, 100), 43, "duplicates are dropped")
+}
+
+func TestInferCategory(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://example.com/":                        "Homepage",
+		"https://example.com/blog/spring-sale":        "Blog",
+		"https://example.com/en/products/blue-shoes":  "Product",
+		"https://example.com/contact-us":              "Contact",
+		"https://example.com/our-services/consulting": "Our services",
+		"https://example.com/12345":                   "Page",
+		"https://example.com/über-uns":                "Über uns",
+		"https://example.com/%C3%B1andu":              "Ñandu",
+	} {
+		assert.Equal(t, want, inferCategory(rawURL, ""), rawURL)
+	}
+
+	head := "<meta charset='utf-8'><title>\n  Frequently asked questions &amp; Help\n</title>"
+	assert.Equal(t, "Frequently asked questions & Help", pageTitle(head))
+	assert.Equal(t, "Help", inferCategory("https://example.com/q", head), "the title is used when the path has no known section")
+
+	results := selectAndCategorizeURLsHeuristically([]string{"https://example.com/", "https://example.com/q"}, map[string]string{"https://example.com/q": head}, 10)
+	assert.Equal(t, []Result{
+		{URL: "https://example.com/", Category: "Homepage"},
+		{URL: "https://example.com/q", Category: "Help"},
+	}, results)
+}
+
+func TestNewServiceWithoutLLM(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("GEMINI_API_KEY", "")
+
+	s, err := NewService()
+	assert.NoError(t, err)
+	assert.False(t, s.UsesLLM())
+}
@@ -1,37 +1,181 @@
 package discovery
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/googleai"
+	"github.com/tmc/langchaingo/llms/openai"
 )
 
-// LLMService provides operations for interacting with an LLM.
+// ErrLLMNotConfigured is returned by NewLLMService when no LLM provider is configured.
+var ErrLLMNotConfigured = errors.New("no LLM provider configured")
+
+// LLM providers.
+const (
+	// ProviderGemini uses the Google Gemini API.
+	ProviderGemini = "gemini"
+	// ProviderOpenAI uses an OpenAI-compatible chat completions API, such as OpenAI itself or a
+	// local Ollama or llama.cpp server.
+	ProviderOpenAI = "openai"
+	// ProviderNone disables the LLM, so that URLs are selected heuristically.
+	ProviderNone = "none"
+)
+
+// DefaultGeminiModel is the model used by the Gemini provider when none is configured.
+const DefaultGeminiModel = "gemini-2.5-flash"
+
+// DefaultLLMMaxTokens is the token limit of a response when none is configured.
+const DefaultLLMMaxTokens = 16384
+
+// URLSelector narrows the URLs of a site down and picks and categorizes the ones to analyze.
+type URLSelector interface {
+	// NarrowDownURLs picks about 20 URLs that cover the different parts of a site.
+	NarrowDownURLs(urls []string, siteCategory string) ([]string, error)
+	// SelectAndCategorizeURLs picks about 10 URLs given their head sections and assigns each a
+	// category.
+	SelectAndCategorizeURLs(urls []string, heads map[string]string, siteCategory string) ([]Result, error)
+}
+
+// LLMConfig selects and configures the LLM of the discovery service.
+type LLMConfig struct {
+	// Provider is ProviderGemini, ProviderOpenAI or ProviderNone.
+	Provider string
+	Model    string
+	// BaseURL is the endpoint of an OpenAI-compatible API, e.g. "http://localhost:11434/v1" for
+	// Ollama. It defaults to the OpenAI API.
+	BaseURL string
+	APIKey  string
+	// MaxTokens limits the length of a response.
+	MaxTokens int
+	// Temperature is left to the model default when nil.
+	Temperature *float64
+}
+
+// LLMConfigFromEnv reads the LLM configuration from the environment:
+//
+//   - LLM_PROVIDER: "gemini", "openai" or "none". Defaults to "gemini" when GEMINI_API_KEY is
+//     set and to "none" otherwise.
+//   - LLM_MODEL: the model name. Defaults to DefaultGeminiModel for Gemini and is required for
+//     OpenAI-compatible endpoints.
+//   - LLM_BASE_URL: the endpoint of an OpenAI-compatible API.
+//   - LLM_API_KEY: the API key, or GEMINI_API_KEY and OPENAI_API_KEY for their provider.
+//   - LLM_MAX_TOKENS: the token limit of a response. Defaults to DefaultLLMMaxTokens.
+//   - LLM_TEMPERATURE: the sampling temperature. Defaults to the model default.
+func LLMConfigFromEnv() (LLMConfig, error) {
+	cfg := LLMConfig{
+		Provider:  os.Getenv("LLM_PROVIDER"),
+		Model:     os.Getenv("LLM_MODEL"),
+		BaseURL:   os.Getenv("LLM_BASE_URL"),
+		APIKey:    os.Getenv("LLM_API_KEY"),
+		MaxTokens: DefaultLLMMaxTokens,
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderNone
+		if os.Getenv("GEMINI_API_KEY") != "" {
+			cfg.Provider = ProviderGemini
+		}
+	}
+	if cfg.APIKey == "" {
+		switch cfg.Provider {
+		case ProviderGemini:
+			cfg.APIKey = os.Getenv("GEMINI_API_KEY")
+		case ProviderOpenAI:
+			cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+	}
+	if v := os.Getenv("LLM_MAX_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return LLMConfig{}, fmt.Errorf("invalid LLM_MAX_TOKENS %q: must be a positive number", v)
+		}
+		cfg.MaxTokens = n
+	}
+	if v := os.Getenv("LLM_TEMPERATURE"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t < 0 {
+			return LLMConfig{}, fmt.Errorf("invalid LLM_TEMPERATURE %q: must be a non-negative number", v)
+		}
+		cfg.Temperature = &t
+	}
+	return cfg, nil
+}
+
+// LLMService selects URLs by prompting an LLM.
 type LLMService struct {
-	client *googleai.GoogleAI
+	client llms.Model
+	config LLMConfig
 }
 
-// NewLLMService creates a new LLM service.
-func NewLLMService() (*LLMService, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY not set")
+// NewLLMService creates a new LLM service for the configured provider. It returns
+// ErrLLMNotConfigured when the provider is ProviderNone.
+func NewLLMService(cfg LLMConfig) (*LLMService, error) {
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultLLMMaxTokens
 	}
 
-	client, err := googleai.New(context.Background(), googleai.WithAPIKey(apiKey))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create googleai client: %w", err)
+	switch cfg.Provider {
+	case "", ProviderNone:
+		return nil, ErrLLMNotConfigured
+	case ProviderGemini:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY not set")
+		}
+		if cfg.Model == "" {
+			cfg.Model = DefaultGeminiModel
+		}
+		client, err := googleai.New(context.Background(), googleai.WithAPIKey(cfg.APIKey), googleai.WithDefaultModel(cfg.Model))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create googleai client: %w", err)
+		}
+		return NewLLMServiceWithModel(client, cfg), nil
+	case ProviderOpenAI:
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("LLM_MODEL must be set for the %s provider", ProviderOpenAI)
+		}
+		opts := []openai.Option{openai.WithModel(cfg.Model)}
+		if cfg.BaseURL != "" {
+			opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+		}
+		switch {
+		case cfg.APIKey != "":
+			opts = append(opts, openai.WithToken(cfg.APIKey))
+		case cfg.BaseURL != "":
+			// Local servers such as Ollama and llama.cpp ignore the key, but the client needs one.
+			opts = append(opts, openai.WithToken("none"))
+		default:
+			return nil, fmt.Errorf("OPENAI_API_KEY or LLM_API_KEY must be set for the OpenAI API")
+		}
+		client, err := openai.New(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create openai client: %w", err)
+		}
+		return NewLLMServiceWithModel(client, cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
 	}
+}
 
-	return &LLMService{client: client}, nil
+// NewLLMServiceWithModel creates a new LLM service that prompts client with the model, token
+// limit and temperature of cfg.
+func NewLLMServiceWithModel(client llms.Model, cfg LLMConfig) *LLMService {
+	return &LLMService{client: client, config: cfg}
 }
 
-// NarrowDownURLs uses the LLM to narrow down a list of URLs to 15.
+// Model returns the name of the model the service prompts.
+func (s *LLMService) Model() string {
+	return s.config.Model
+}
+
+// NarrowDownURLs uses the LLM to narrow down a list of URLs to 20.
 func (s *LLMService) NarrowDownURLs(urls []string, siteCategory string) ([]string, error) {
 	prompt := fmt.Sprintf(
 		"From the following list of URLs, select the 20 most relevant URLs for a site also exploring different categories '%s'.\n\nURLs:\n%v\n\nReturn a json list of selected URLs.",
@@ -39,23 +183,11 @@ func (s *LLMService) NarrowDownURLs(urls []string, siteCategory string) ([]strin
 		strings.Join(urls, "\n"),
 	)
 
-	resp, err := s.client.GenerateContent(context.Background(),
-		[]llms.MessageContent{
-			{
-				Role: llms.ChatMessageTypeHuman,
-				Parts: []llms.ContentPart{
-					llms.TextContent{Text: prompt},
-				},
-			},
-		},
-		llms.WithMaxTokens(12048), llms.WithModel("gemini-2.5-flash"),
-		llms.WithJSONMode(),
-	)
+	content, err := s.generate(prompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call LLM: %w", err)
+		return nil, err
 	}
-
-	return parseJSONURLs(resp.Choices[0].Content)
+	return parseJSONURLs(content)
 }
 
 // SelectAndCategorizeURLs uses the LLM to select 10 URLs and assign categories.
@@ -71,6 +203,24 @@ func (s *LLMService) SelectAndCategorizeURLs(urls []string, heads map[string]str
 
 	prompt += "Return the result as a JSON array of objects, where each object has 'url' and 'category' keys. For example: [{\"url\": \"https://example.com\", \"category\": \"e-commerce\"}]"
 
+	content, err := s.generate(prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseJSONResponse(content)
+}
+
+// generate sends a prompt to the LLM in JSON mode and returns its answer.
+func (s *LLMService) generate(prompt string) (string, error) {
+	opts := []llms.CallOption{
+		llms.WithModel(s.config.Model),
+		llms.WithMaxTokens(s.config.MaxTokens),
+		llms.WithJSONMode(),
+	}
+	if s.config.Temperature != nil {
+		opts = append(opts, llms.WithTemperature(*s.config.Temperature))
+	}
+
 	resp, err := s.client.GenerateContent(context.Background(),
 		[]llms.MessageContent{
 			{
@@ -80,25 +230,21 @@ func (s *LLMService) SelectAndCategorizeURLs(urls []string, heads map[string]str
 				},
 			},
 		},
-		llms.WithMaxTokens(14096), llms.WithModel("gemini-2.5-flash"),
-		llms.WithJSONMode(),
+		opts...,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call LLM: %w", err)
+		return "", fmt.Errorf("failed to call LLM: %w", err)
 	}
-
-	return parseJSONResponse(resp.Choices[0].Content)
+	if resp == nil || len(resp.Choices) == 0 {
+		return "", fmt.Errorf("LLM returned no answer")
+	}
+	return resp.Choices[0].Content, nil
 }
 
 func parseJSONURLs(in string) ([]string, error) {
 	// Parse JSON response containing a list of URL strings
-	// The LLM can return a markdown code block, so we need to trim it.
-	in = strings.TrimPrefix(in, "```json")
-	in = strings.TrimSuffix(in, "```")
-	in = strings.TrimSpace(in)
-
 	var urls []string
-	err := json.Unmarshal([]byte(in), &urls)
+	err := json.Unmarshal(jsonArray(in), &urls)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
@@ -119,17 +265,41 @@ func parseCommaSeparated(in string) []string {
 func parseJSONResponse(in string) ([]Result, error) {
 	// Basic parsing, assuming a simple JSON array.
 	// In a real-world scenario, this would need to be more robust.
-	// The LLM can return a markdown code block, so we need to trim it.
-	in = strings.TrimPrefix(in, "```json")
-	in = strings.TrimSuffix(in, "```")
 	var results []Result
-	err := json.Unmarshal([]byte(in), &results)
+	err := json.Unmarshal(jsonArray(in), &results)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 	return results, nil
 }
 
+// jsonArray extracts the JSON array from an LLM answer. The answer can be wrapped in a markdown
+// code block, and OpenAI-compatible APIs in JSON mode wrap arrays in an object such as
+// {"urls": [...]}, in which case the array property is returned.
+func jsonArray(in string) []byte {
+	in = strings.TrimSpace(in)
+	in = strings.TrimPrefix(in, "```json")
+	in = strings.TrimSuffix(in, "```")
+	in = strings.TrimSpace(in)
+
+	if strings.HasPrefix(in, "{") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(in), &object); err == nil {
+			keys := make([]string, 0, len(object))
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if value := bytes.TrimSpace(object[key]); len(value) > 0 && value[0] == '[' {
+					return value
+				}
+			}
+		}
+	}
+	return []byte(in)
+}
+
 func splitAndTrim(s, sep string) []string {
 	var result []string
 	for _, item := range strings.Split(s, sep) {
@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeModel answers every prompt with the same content and records the call options.
+type fakeModel struct {
+	content string
+	err     error
+	prompts []string
+	options llms.CallOptions
+}
+
+func (m *fakeModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	for _, part := range messages[0].Parts {
+		m.prompts = append(m.prompts, part.(llms.TextContent).Text)
+	}
+	for _, opt := range options {
+		opt(&m.options)
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.content}}}, nil
+}
+
+func (m *fakeModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestLLMServiceOptions(t *testing.T) {
+	temperature := 0.2
+	model := &fakeModel{content: "```json\n[\"https://example.com/a\", \"https://example.com/b\"]\n```"}
+	s := NewLLMServiceWithModel(model, LLMConfig{Model: "llama3.1", MaxTokens: 2048, Temperature: &temperature})
+
+	urls, err := s.NarrowDownURLs([]string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}, "e-commerce")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, urls)
+	assert.Contains(t, model.prompts[0], "https://example.com/c")
+	assert.Equal(t, "llama3.1", model.options.Model)
+	assert.Equal(t, 2048, model.options.MaxTokens)
+	assert.Equal(t, 0.2, model.options.Temperature)
+	assert.True(t, model.options.JSONMode)
+}
+
+func TestLLMServiceParsesWrappedArrays(t *testing.T) {
+	model := &fakeModel{content: `{"results": [{"url": "https://example.com/", "category": "homepage"}]}`}
+	s := NewLLMServiceWithModel(model, LLMConfig{Model: "gpt-4o-mini"})
+
+	results, err := s.SelectAndCategorizeURLs([]string{"https://example.com/"}, map[string]string{"https://example.com/": "<title>Home</title>"}, "shop")
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{{URL: "https://example.com/", Category: "homepage"}}, results)
+	assert.Contains(t, model.prompts[0], "<title>Home</title>")
+
+	model.err = errors.New("connection refused")
+	_, err = s.NarrowDownURLs([]string{"https://example.com/"}, "shop")
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestLLMConfigFromEnv(t *testing.T) {
+	for _, name := range []string{"LLM_PROVIDER", "LLM_MODEL", "LLM_BASE_URL", "LLM_API_KEY", "LLM_MAX_TOKENS", "LLM_TEMPERATURE", "GEMINI_API_KEY", "OPENAI_API_KEY"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := LLMConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderNone, cfg.Provider)
+	assert.Equal(t, DefaultLLMMaxTokens, cfg.MaxTokens)
+	assert.Nil(t, cfg.Temperature)
+
+	t.Setenv("GEMINI_API_KEY", "gemini-key")
+	cfg, err = LLMConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderGemini, cfg.Provider)
+	assert.Equal(t, "gemini-key", cfg.APIKey)
+
+	t.Setenv("LLM_PROVIDER", ProviderOpenAI)
+	t.Setenv("LLM_MODEL", "llama3.1")
+	t.Setenv("LLM_BASE_URL", "http://localhost:11434/v1")
+	t.Setenv("LLM_MAX_TOKENS", "4096")
+	t.Setenv("LLM_TEMPERATURE", "0")
+	cfg, err = LLMConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderOpenAI, cfg.Provider)
+	assert.Equal(t, "", cfg.APIKey, "the Gemini key is not sent to other providers")
+	assert.Equal(t, 4096, cfg.MaxTokens)
+	assert.Equal(t, 0.0, *cfg.Temperature)
+
+	t.Setenv("LLM_MAX_TOKENS", "lots")
+	_, err = LLMConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestNewLLMService(t *testing.T) {
+	_, err := NewLLMService(LLMConfig{Provider: ProviderNone})
+	assert.ErrorIs(t, err, ErrLLMNotConfigured)
+
+	_, err = NewLLMService(LLMConfig{Provider: ProviderGemini})
+	assert.Error(t, err, "Gemini needs an API key")
+
+	_, err = NewLLMService(LLMConfig{Provider: ProviderOpenAI, BaseURL: "http://localhost:11434/v1"})
+	assert.Error(t, err, "OpenAI-compatible endpoints need a model")
+
+	_, err = NewLLMService(LLMConfig{Provider: ProviderOpenAI, Model: "gpt-4o-mini"})
+	assert.Error(t, err, "the OpenAI API needs a key")
+
+	s, err := NewLLMService(LLMConfig{Provider: ProviderOpenAI, Model: "llama3.1", BaseURL: "http://localhost:11434/v1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "llama3.1", s.Model())
+
+	s, err = NewLLMService(LLMConfig{Provider: ProviderGemini, APIKey: "key"})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultGeminiModel, s.Model())
+
+	_, err = NewLLMService(LLMConfig{Provider: "claude"})
+	assert.ErrorContains(t, err, "unknown LLM provider")
+}
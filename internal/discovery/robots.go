@@ -0,0 +1,250 @@
+package discovery
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserAgent identifies discovery requests. Its product token, "pa11y-go-wrapper", selects the
+// robots.txt rules that apply to them.
+const UserAgent = "pa11y-go-wrapper/1.0"
+
+// maxRobotsSize is how much of a robots.txt file is read, the limit set by RFC 9309.
+const maxRobotsSize = 500 << 10
+
+// maxCrawlDelay caps the Crawl-delay of a robots.txt file, so that a discovery cannot stall for
+// minutes on one site.
+const maxCrawlDelay = 10 * time.Second
+
+// Robots holds the directives of a robots.txt file that apply to one user agent.
+type Robots struct {
+	// Sitemaps are the URLs of the sitemaps declared by Sitemap lines.
+	Sitemaps []string
+	// CrawlDelay is the pause the site asks for between two requests, capped at 10 seconds.
+	CrawlDelay time.Duration
+	rules      []robotsRule
+}
+
+// robotsRule is an Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsGroup is a group of rules with the user agents it applies to.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// ParseRobots parses a robots.txt file and keeps the rules that apply to userAgent: those of the
+// groups naming its product token, e.g. "pa11y-go-wrapper", or else those of the "*" groups.
+func ParseRobots(r io.Reader, userAgent string) (*Robots, error) {
+	robots := &Robots{}
+	var groups []*robotsGroup
+	var group *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				group = &robotsGroup{}
+				groups = append(groups, group)
+				inAgents = true
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			continue
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = appendUnique(robots.Sitemaps, value)
+			}
+		case "allow", "disallow":
+			if group != nil && value != "" {
+				group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); group != nil && err == nil && seconds > 0 {
+				group.crawlDelay = min(time.Duration(seconds*float64(time.Second)), maxCrawlDelay)
+			}
+		}
+		inAgents = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+	}
+
+	token, _, _ := strings.Cut(strings.ToLower(userAgent), "/")
+	matched := groupsFor(groups, token)
+	if matched == nil {
+		matched = groupsFor(groups, "*")
+	}
+	for _, g := range matched {
+		robots.rules = append(robots.rules, g.rules...)
+		robots.CrawlDelay = max(robots.CrawlDelay, g.crawlDelay)
+	}
+	return robots, nil
+}
+
+// groupsFor returns the groups that name a user agent.
+func groupsFor(groups []*robotsGroup, agent string) []*robotsGroup {
+	var matched []*robotsGroup
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == agent {
+				matched = append(matched, g)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Allowed reports whether the rules let the user agent fetch a URL. The most specific rule
+// matching its path wins, and Allow wins a tie; URLs that no rule matches are allowed. A nil
+// Robots allows everything.
+func (r *Robots) Allowed(rawURL string) bool {
+	if r == nil || len(r.rules) == 0 {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if target == "/robots.txt" {
+		return true
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, target) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// Filter returns the URLs the rules allow, in order.
+func (r *Robots) Filter(urls []string) []string {
+	var allowed []string
+	for _, u := range urls {
+		if r.Allowed(u) {
+			allowed = append(allowed, u)
+		}
+	}
+	return allowed
+}
+
+// matchRobotsPattern reports whether a robots.txt path pattern matches the start of a path,
+// where "*" matches any sequence of characters and a final "$" anchors the pattern at the end.
+func matchRobotsPattern(pattern, target string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(target, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return part == "" || (strings.HasSuffix(target, part) && len(target)-len(part) >= pos)
+		}
+		j := strings.Index(target[pos:], part)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(part)
+	}
+	return !anchored || pos == len(target)
+}
+
+// fetchRobots fetches and parses the robots.txt file of a site. A site without one, or whose
+// robots.txt cannot be fetched, has no rules, which allows everything.
+func fetchRobots(client *http.Client, siteURL string) *Robots {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return &Robots{}
+	}
+	robotsURL := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+
+	resp, err := get(client, robotsURL)
+	if err != nil {
+		fmt.Printf("failed to fetch %s: %v\n", robotsURL, err)
+		return &Robots{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &Robots{}
+	}
+
+	robots, err := ParseRobots(resp.Body, UserAgent)
+	if err != nil {
+		fmt.Printf("failed to parse %s: %v\n", robotsURL, err)
+		return &Robots{}
+	}
+	return robots
+}
+
+// pacer spaces out the requests to a site.
+type pacer struct {
+	delay time.Duration
+	last  time.Time
+}
+
+// wait blocks until delay has passed since the previous request, if any, and records the
+// request about to be made.
+func (p *pacer) wait() {
+	if !p.last.IsZero() {
+		if d := time.Until(p.last.Add(p.delay)); d > 0 {
+			time.Sleep(d)
+		}
+	}
+	p.last = time.Now()
+}
+
+// get fetches a URL with the discovery user agent.
+func get(client *http.Client, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	return client.Do(req)
+}
+
+// appendUnique appends a value to a list unless it is already in it.
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
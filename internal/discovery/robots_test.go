@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRobots = `# Rules for everyone
+User-agent: *
+Disallow: /
+Crawl-delay: 30
+
+User-agent: Googlebot
+User-agent: PA11Y-GO-WRAPPER
+Disallow: /private # staff only
+Disallow: /*.php$
+Disallow: /search?
+Allow: /private/press
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/pages.txt.gz
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseRobots(t *testing.T) {
+	robots, err := ParseRobots(strings.NewReader(testRobots), UserAgent)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/sitemap.xml", "https://example.com/pages.txt.gz"}, robots.Sitemaps)
+	assert.Equal(t, 500*time.Millisecond, robots.CrawlDelay)
+
+	tests := map[string]bool{
+		"https://example.com/":                       true,
+		"https://example.com/about":                  true,
+		"https://example.com/private":                false,
+		"https://example.com/private/staff":          false,
+		"https://example.com/private/press":          true,
+		"https://example.com/index.php":              false,
+		"https://example.com/index.php?page=2":       true,
+		"https://example.com/search?q=accessibility": false,
+		"https://example.com/search":                 true,
+		"https://example.com/robots.txt":             true,
+	}
+	for u, want := range tests {
+		assert.Equal(t, want, robots.Allowed(u), u)
+	}
+}
+
+func TestParseRobotsFallsBackToWildcardGroup(t *testing.T) {
+	robots, err := ParseRobots(strings.NewReader(testRobots), "OtherBot/2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, maxCrawlDelay, robots.CrawlDelay)
+	assert.False(t, robots.Allowed("https://example.com/about"))
+	assert.True(t, robots.Allowed("https://example.com/robots.txt"))
+
+	var none *Robots
+	assert.True(t, none.Allowed("https://example.com/about"))
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		target  string
+		want    bool
+	}{
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish*", "/fishheads", true},
+		{"/fish/", "/fish", false},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php/", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchRobotsPattern(tt.pattern, tt.target), "%s %s", tt.pattern, tt.target)
+	}
+}
+
+func TestDiscoverHonoursRobots(t *testing.T) {
+	var server *httptest.Server
+	var mu sync.Mutex
+	var fetched []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "User-agent: *\nDisallow: /private\n\nSitemap: %[1]s/pages.xml\nSitemap: %[1]s/more.txt.gz\n", server.URL)
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset><url><loc>%[1]s/</loc></url><url><loc>%[1]s/private/report</loc></url></urlset>`, server.URL)
+	})
+	mux.HandleFunc("/more.txt.gz", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		fmt.Fprintf(gz, "%[1]s/contact\n\n%[1]s/private/staff\n", server.URL)
+		gz.Close()
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched = append(fetched, r.URL.Path)
+		mu.Unlock()
+		assert.Equal(t, UserAgent, r.UserAgent())
+		fmt.Fprintf(w, "<html><head><title>Page %s</title></head><body></body></html>", r.URL.Path)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	results, err := NewServiceWithSelector(nil).Discover(server.URL, "blog")
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{URL: server.URL + "/", Status: "200 OK", Category: "Homepage"},
+		{URL: server.URL + "/contact", Status: "200 OK", Category: "Contact"},
+	}, results)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.NotContains(t, strings.Join(fetched, " "), "/private")
+}
+
+func TestCrawlHonoursRobots(t *testing.T) {
+	site := newLinkedSite(t, map[string]string{
+		"/":        `<a href="/private">Private</a><a href="/public">Public</a>`,
+		"/private": ``,
+		"/public":  ``,
+	})
+	robots, err := ParseRobots(strings.NewReader("User-agent: *\nDisallow: /private\n"), UserAgent)
+	assert.NoError(t, err)
+
+	crawler := NewCrawler(CrawlOptions{Delay: -1})
+	crawler.SetRobots(robots)
+	pages, err := crawler.Crawl(site.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{site.URL + "/", site.URL + "/public"}, pages)
+}
+
+func TestRequestsWaitForCrawlDelay(t *testing.T) {
+	const delay = 150 * time.Millisecond
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			panic(http.ErrAbortHandler)
+		}
+		fmt.Fprint(w, "<html><head><title>Page</title></head></html>")
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewServiceWithSelector(nil)
+	pace := &pacer{delay: delay}
+	heads, err := s.extractHeads([]string{server.URL + "/broken", server.URL + "/a"}, &Robots{}, pace)
+	assert.NoError(t, err)
+	assert.Equal(t, "", heads[server.URL+"/broken"])
+	assert.Equal(t, "<title>Page</title>", heads[server.URL+"/a"])
+	assert.Equal(t, "200 OK", s.checkURLStatus(server.URL+"/a", pace))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, times, 3)
+	for i := 1; i < len(times); i++ {
+		assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), delay-10*time.Millisecond, "request %d", i)
+	}
+}
@@ -0,0 +1,58 @@
+package discovery
+
+import "sync"
+
+// scriptedSelector is a URLSelector that plays back scripted answers instead of prompting an
+// LLM.
+type scriptedSelector struct {
+	// Narrowed is returned by NarrowDownURLs. When nil, the URLs it is given are returned.
+	Narrowed []string
+	// Selected is returned by SelectAndCategorizeURLs. When nil, every URL it is given is
+	// selected with the category of the site.
+	Selected []Result
+	// Err, when set, is returned by both methods instead.
+	Err error
+
+	mu         sync.Mutex
+	narrowCall []string
+	selectCall []string
+}
+
+// NarrowDownURLs returns the scripted URLs.
+func (s *scriptedSelector) NarrowDownURLs(urls []string, siteCategory string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.narrowCall = append([]string(nil), urls...)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if s.Narrowed == nil {
+		return urls, nil
+	}
+	return s.Narrowed, nil
+}
+
+// SelectAndCategorizeURLs returns the scripted results.
+func (s *scriptedSelector) SelectAndCategorizeURLs(urls []string, heads map[string]string, siteCategory string) ([]Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.selectCall = append([]string(nil), urls...)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if s.Selected == nil {
+		results := make([]Result, 0, len(urls))
+		for _, u := range urls {
+			results = append(results, Result{URL: u, Category: siteCategory})
+		}
+		return results, nil
+	}
+	return append([]Result(nil), s.Selected...), nil
+}
+
+// Calls returns the URLs passed to the last NarrowDownURLs and SelectAndCategorizeURLs calls.
+func (s *scriptedSelector) Calls() (narrowed, selected []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.narrowCall, s.selectCall
+}
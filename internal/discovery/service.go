@@ -1,7 +1,10 @@
 package discovery
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -15,16 +18,43 @@ import (
 
 // Service provides operations for discovering URLs from a sitemap.
 type Service struct {
-	llmService *LLMService
+	// selector is nil when no LLM is configured, in which case URLs are selected heuristically.
+	selector URLSelector
+	// crawlOptions bound the crawl of sites without a sitemap.
+	crawlOptions CrawlOptions
 }
 
-// NewService creates a new discovery service.
+// NewService creates a new discovery service with the LLM configured in the environment (see
+// LLMConfigFromEnv). Without one, it selects URLs heuristically.
 func NewService() (*Service, error) {
-	llmService, err := NewLLMService()
+	cfg, err := LLMConfigFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	return &Service{llmService: llmService}, nil
+	llmService, err := NewLLMService(cfg)
+	if errors.Is(err, ErrLLMNotConfigured) {
+		return &Service{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Service{selector: llmService}, nil
+}
+
+// NewServiceWithSelector creates a new discovery service that selects URLs with selector, or
+// heuristically if it is nil.
+func NewServiceWithSelector(selector URLSelector) *Service {
+	return &Service{selector: selector}
+}
+
+// SetCrawlOptions sets the limits of the crawl of sites without a sitemap.
+func (s *Service) SetCrawlOptions(options CrawlOptions) {
+	s.crawlOptions = options
+}
+
+// UsesLLM reports whether the service selects URLs with an LLM rather than heuristically.
+func (s *Service) UsesLLM() bool {
+	return s.selector != nil
 }
 
 // Result represents a discovered URL with its status.
@@ -34,42 +64,81 @@ type Result struct {
 	Category string `json:"category"`
 }
 
-// Discover fetches and parses a sitemap to discover URLs, then uses an LLM to refine the list.
+// Discover fetches and parses the sitemaps of a site to discover URLs, or crawls the site when it
+// has none, then refines the list with an LLM or, without one, heuristically. The rules and
+// Crawl-delay of its robots.txt file are honoured throughout.
 func (s *Service) Discover(siteURL string, siteCategory string) ([]Result, error) {
-	// 1. Get initial list of URLs from sitemap
-	initialURLs, err := s.getURLsFromSitemap(siteURL)
+	// 1. Get initial list of URLs from sitemap, or by crawling the site, as robots.txt allows
+	robots := fetchRobots(http.DefaultClient, siteURL)
+	initialURLs, err := s.getURLsFromSitemap(siteURL, robots)
+	if err == nil && len(initialURLs) == 0 {
+		err = errors.New("sitemap lists no URLs")
+	}
 	if err != nil {
-		return nil, err
+		fmt.Printf("no usable sitemap for %s, crawling the site: %v\n", siteURL, err)
+		crawler := NewCrawler(s.crawlOptions)
+		crawler.SetRobots(robots)
+		initialURLs, err = crawler.Crawl(siteURL)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// 2. Sample URLs if there are more than 200
 	initialURLs = s.sampleUrls(siteURL, initialURLs)
 
-	// 3. Narrow down to 15 URLs using LLM
-	narrowedURLs, err := s.llmService.NarrowDownURLs(initialURLs, siteCategory)
-	if err != nil {
-		return nil, err
-	}
+	// 3. Narrow down to 20 URLs
+	narrowedURLs := s.narrowDownURLs(initialURLs, siteCategory)
 
-	// 4. Extract head section for each of the 15 URLs
-	heads, err := s.extractHeads(narrowedURLs)
+	// 4. Extract head section for each of the 20 URLs, pausing between requests for the
+	// Crawl-delay of robots.txt or 100ms, whichever is longer
+	pace := &pacer{delay: max(100*time.Millisecond, robots.CrawlDelay)}
+	heads, err := s.extractHeads(narrowedURLs, robots, pace)
 	if err != nil {
 		return nil, err
 	}
 
-	// 5. Select and categorize 10 URLs using LLM
-	finalResults, err := s.llmService.SelectAndCategorizeURLs(narrowedURLs, heads, siteCategory)
-	if err != nil {
-		return nil, err
-	}
+	// 5. Select and categorize 10 URLs
+	finalResults := s.selectAndCategorizeURLs(narrowedURLs, heads, siteCategory)
+
 	// 6. check the status for each URL
 	for i := range finalResults {
-		finalResults[i].Status = s.checkURLStatus(finalResults[i].URL)
+		if !robots.Allowed(finalResults[i].URL) {
+			finalResults[i].Status = "Disallowed by robots.txt"
+			continue
+		}
+		finalResults[i].Status = s.checkURLStatus(finalResults[i].URL, pace)
 	}
 
 	return finalResults, nil
 }
 
+// narrowDownURLs narrows the URLs of a site down with the LLM, falling back to heuristics when
+// there is no LLM or it fails.
+func (s *Service) narrowDownURLs(urls []string, siteCategory string) []string {
+	if s.selector != nil {
+		narrowed, err := s.selector.NarrowDownURLs(urls, siteCategory)
+		if err == nil {
+			return narrowed
+		}
+		fmt.Printf("LLM failed to narrow down URLs, selecting them heuristically: %v\n", err)
+	}
+	return narrowDownURLsHeuristically(urls, narrowedURLCount)
+}
+
+// selectAndCategorizeURLs selects and categorizes the final URLs with the LLM, falling back to
+// heuristics when there is no LLM or it fails.
+func (s *Service) selectAndCategorizeURLs(urls []string, heads map[string]string, siteCategory string) []Result {
+	if s.selector != nil {
+		results, err := s.selector.SelectAndCategorizeURLs(urls, heads, siteCategory)
+		if err == nil {
+			return results
+		}
+		fmt.Printf("LLM failed to select URLs, selecting them heuristically: %v\n", err)
+	}
+	return selectAndCategorizeURLsHeuristically(urls, heads, selectedURLCount)
+}
+
 // sampleUrls samples URLs if there are more than 200:
 // - Takes first 20 shortest URLs (ordered by length)
 // - Takes 50 random URLs from the remaining ones
@@ -117,13 +186,47 @@ func (s *Service) sampleUrls(siteURL string, urls []string) []string {
 	return result
 }
 
-func (s *Service) getURLsFromSitemap(siteURL string) ([]string, error) {
-	sitemapURL := fmt.Sprintf("%s/sitemap.xml", siteURL)
-	return s.parseXMLSitemap(sitemapURL)
+// getURLsFromSitemap reads the sitemaps declared in the robots.txt file of a site, or its
+// /sitemap.xml when it declares none, and returns the URLs they list that robots.txt allows.
+func (s *Service) getURLsFromSitemap(siteURL string, robots *Robots) ([]string, error) {
+	sitemapURLs := robots.Sitemaps
+	if len(sitemapURLs) == 0 {
+		sitemapURLs = []string{fmt.Sprintf("%s/sitemap.xml", strings.TrimSuffix(siteURL, "/"))}
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	var lastErr error
+	for _, sitemapURL := range sitemapURLs {
+		listed, err := s.parseSitemap(sitemapURL)
+		if err != nil {
+			fmt.Printf("failed to parse sitemap %s: %v\n", sitemapURL, err)
+			lastErr = err
+			continue
+		}
+		for _, u := range listed {
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	if len(urls) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	allowed := robots.Filter(urls)
+	if skipped := len(urls) - len(allowed); skipped > 0 {
+		fmt.Printf("skipped %d sitemap URLs disallowed by robots.txt\n", skipped)
+	}
+	return allowed, nil
 }
 
-func (s *Service) parseXMLSitemap(sitemapURL string) ([]string, error) {
-	resp, err := http.Get(sitemapURL)
+// parseSitemap fetches a sitemap and returns the URLs it lists. Sitemaps can be XML url sets,
+// XML sitemap indexes, whose sitemaps are read in turn, or text files with one URL per line, and
+// any of them can be gzipped.
+func (s *Service) parseSitemap(sitemapURL string) ([]string, error) {
+	resp, err := get(http.DefaultClient, sitemapURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
 	}
@@ -133,10 +236,11 @@ func (s *Service) parseXMLSitemap(sitemapURL string) ([]string, error) {
 		return nil, fmt.Errorf("sitemap not found or accessible, status code: %d", resp.StatusCode)
 	}
 
-	// Handle gzipped response body
-	var reader io.Reader = resp.Body
-	if resp.Header.Get("Content-Encoding") == "gzip" || strings.HasSuffix(sitemapURL, ".gz") {
-		gzipReader, err := gzip.NewReader(resp.Body)
+	// Handle gzipped response body, recognized by its magic number
+	body := bufio.NewReader(resp.Body)
+	var reader io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
 		}
@@ -144,8 +248,16 @@ func (s *Service) parseXMLSitemap(sitemapURL string) ([]string, error) {
 		reader = gzipReader
 	}
 
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap: %w", err)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		return parseTextSitemap(content), nil
+	}
+
 	doc := etree.NewDocument()
-	if _, err := doc.ReadFrom(reader); err != nil {
+	if err := doc.ReadFromBytes(content); err != nil {
 		return nil, fmt.Errorf("failed to parse sitemap XML: %w", err)
 	}
 
@@ -158,7 +270,7 @@ func (s *Service) parseXMLSitemap(sitemapURL string) ([]string, error) {
 		for _, sitemapElement := range sitemapIndex.SelectElements("sitemap") {
 			loc := sitemapElement.SelectElement("loc")
 			if loc != nil {
-				subSitemapURLs, err := s.parseXMLSitemap(loc.Text())
+				subSitemapURLs, err := s.parseSitemap(strings.TrimSpace(loc.Text()))
 				if err != nil {
 					// Log error but continue with other sitemaps
 					fmt.Printf("failed to parse sub-sitemap %s: %v\n", loc.Text(), err)
@@ -176,7 +288,7 @@ func (s *Service) parseXMLSitemap(sitemapURL string) ([]string, error) {
 		for _, urlElement := range urlset.SelectElements("url") {
 			loc := urlElement.SelectElement("loc")
 			if loc != nil {
-				urls = append(urls, loc.Text())
+				urls = append(urls, strings.TrimSpace(loc.Text()))
 			}
 		}
 		return urls, nil
@@ -185,10 +297,30 @@ func (s *Service) parseXMLSitemap(sitemapURL string) ([]string, error) {
 	return nil, fmt.Errorf("invalid sitemap format: neither <sitemapindex> nor <urlset> found")
 }
 
-func (s *Service) extractHeads(urls []string) (map[string]string, error) {
+// parseTextSitemap returns the URLs of a text sitemap, one per line.
+func parseTextSitemap(content []byte) []string {
+	var urls []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			urls = append(urls, line)
+		}
+	}
+	return urls
+}
+
+// extractHeads fetches the head section of each URL that robots.txt allows, waiting for pace
+// before every request.
+func (s *Service) extractHeads(urls []string, robots *Robots, pace *pacer) (map[string]string, error) {
 	heads := make(map[string]string)
 	for _, url := range urls {
-		resp, err := http.Get(url)
+		if !robots.Allowed(url) {
+			fmt.Printf("skipping URL %s disallowed by robots.txt\n", url)
+			heads[url] = ""
+			continue
+		}
+		pace.wait()
+		resp, err := get(http.DefaultClient, url)
 		if err != nil {
 			// It's better to log this error and continue
 			fmt.Printf("failed to get URL %s: %v\n", url, err)
@@ -214,13 +346,14 @@ func (s *Service) extractHeads(urls []string) (map[string]string, error) {
 		} else {
 			heads[url] = ""
 		}
-		time.Sleep(100 * time.Millisecond) // Delay between calls
 	}
 	return heads, nil
 }
 
-func (s *Service) checkURLStatus(url string) string {
-	resp, err := http.Get(url)
+// checkURLStatus fetches a URL, waiting for pace first, and returns the status of the response.
+func (s *Service) checkURLStatus(url string, pace *pacer) string {
+	pace.wait()
+	resp, err := get(http.DefaultClient, url)
 	if err != nil {
 		return fmt.Sprintf("Error: %s", err.Error())
 	}
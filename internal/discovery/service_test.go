@@ -0,0 +1,64 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSite serves a sitemap listing the given paths, each answering with a page titled after
+// its path.
+func newTestSite(t *testing.T, paths ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		for _, path := range paths {
+			fmt.Fprintf(w, "<url><loc>%s%s</loc></url>", server.URL, path)
+		}
+		fmt.Fprint(w, "</urlset>")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><head><title>Page %s</title></head><body></body></html>", r.URL.Path)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscoverWithSelector(t *testing.T) {
+	site := newTestSite(t, "/", "/about", "/blog/first-post")
+	selector := &scriptedSelector{
+		Narrowed: []string{site.URL + "/", site.URL + "/about"},
+		Selected: []Result{{URL: site.URL + "/about", Category: "company"}},
+	}
+
+	results, err := NewServiceWithSelector(selector).Discover(site.URL, "blog")
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{{URL: site.URL + "/about", Status: "200 OK", Category: "company"}}, results)
+
+	narrowed, selected := selector.Calls()
+	assert.Len(t, narrowed, 3)
+	assert.Equal(t, []string{site.URL + "/", site.URL + "/about"}, selected)
+}
+
+func TestDiscoverFallsBackToHeuristics(t *testing.T) {
+	site := newTestSite(t, "/", "/contact", "/q")
+
+	for name, s := range map[string]*Service{
+		"without LLM": NewServiceWithSelector(nil),
+		"failing LLM": NewServiceWithSelector(&scriptedSelector{Err: errors.New("quota exceeded")}),
+	} {
+		results, err := s.Discover(site.URL, "blog")
+		assert.NoError(t, err, name)
+		assert.Equal(t, []Result{
+			{URL: site.URL + "/", Status: "200 OK", Category: "Homepage"},
+			{URL: site.URL + "/contact", Status: "200 OK", Category: "Contact"},
+			{URL: site.URL + "/q", Status: "200 OK", Category: "Q"},
+		}, results, name)
+	}
+}
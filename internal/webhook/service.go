@@ -0,0 +1,466 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"pa11y-go-wrapper/internal/analysis"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event names a webhook can subscribe to.
+const (
+	// EventCompleted fires when an analysis completes.
+	EventCompleted = "completed"
+	// EventFailed fires when an analysis fails.
+	EventFailed = "failed"
+	// EventThresholdExceeded fires when a completed analysis has more errors than the
+	// webhook's threshold.
+	EventThresholdExceeded = "threshold-exceeded"
+)
+
+var validEvents = []string{EventCompleted, EventFailed, EventThresholdExceeded}
+
+// Headers set on every delivery.
+const (
+	SignatureHeader = "X-Pa11y-Signature"
+	EventHeader     = "X-Pa11y-Event"
+	DeliveryHeader  = "X-Pa11y-Delivery"
+)
+
+var (
+	// ErrNotFound is returned when a webhook does not exist.
+	ErrNotFound = errors.New("webhook not found")
+	// ErrInvalidWebhook is returned when a webhook subscription is not valid.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+)
+
+// maxDeliveriesPerWebhook caps the delivery records kept for each webhook.
+const maxDeliveriesPerWebhook = 50
+
+// Collections of the webhooks and of their delivery records in a record store.
+const (
+	webhooksCollection   = "webhooks"
+	deliveriesCollection = "webhook-deliveries"
+)
+
+// Webhook is a subscription to analysis events delivered to an HTTP endpoint.
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Events lists the events delivered to the webhook.
+	Events []string `json:"events"`
+	// Secret signs every delivery with HMAC-SHA256. It is only returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+	// Threshold is the number of errors a completed analysis may have before a
+	// threshold-exceeded event fires.
+	Threshold int       `json:"threshold,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Attempt records one try at delivering an event.
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Delivery records the delivery of one event to one webhook.
+type Delivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhookId"`
+	Event      string    `json:"event"`
+	AnalysisID string    `json:"analysisId"`
+	Delivered  bool      `json:"delivered"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Attempts   []Attempt `json:"attempts"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// storedWebhook is a webhook as written to a record store: without its secret, which is sealed
+// when a sealer is configured.
+type storedWebhook struct {
+	Webhook
+	SealedSecret []byte `json:"sealedSecret,omitempty"`
+}
+
+// errSecretLost is recorded on the deliveries of a webhook whose secret was kept in memory only
+// and lost on restart.
+var errSecretLost = errors.New("signing secret was not kept across a restart; create the webhook again")
+
+// Payload is the JSON body posted to webhooks.
+type Payload struct {
+	Event      string         `json:"event"`
+	DeliveryID string         `json:"deliveryId"`
+	Timestamp  time.Time      `json:"timestamp"`
+	Analysis   analysis.Event `json:"analysis"`
+}
+
+// Service manages webhook subscriptions and delivers analysis events to them.
+type Service struct {
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	// store persists webhooks and their deliveries, or is nil to keep them in memory only.
+	store analysis.RecordStore
+	// sealer encrypts the stored secrets, or is nil to keep the secrets in memory only.
+	sealer *analysis.Sealer
+
+	mu         sync.Mutex
+	webhooks   map[string]*Webhook
+	deliveries map[string][]*Delivery
+}
+
+// NewService creates a webhook service. Deliveries are attempted up to 5 times, waiting
+// 1s, 2s, 4s and 8s between attempts.
+func NewService() *Service {
+	return &Service{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 5,
+		baseDelay:   time.Second,
+		webhooks:    make(map[string]*Webhook),
+		deliveries:  make(map[string][]*Delivery),
+	}
+}
+
+// NewServiceWithStore creates a webhook service that persists webhooks and their delivery
+// records in store, starting with those it already holds. Signing secrets are never stored in
+// plaintext: they are encrypted with sealer, or kept in memory only if it is nil, in which case
+// deliveries of webhooks created before a restart fail until they are created again.
+func NewServiceWithStore(store analysis.RecordStore, sealer *analysis.Sealer) (*Service, error) {
+	s := NewService()
+	s.store = store
+	s.sealer = sealer
+	for id, data := range store.Records(webhooksCollection) {
+		var stored storedWebhook
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook %s: %w", id, err)
+		}
+		webhook := stored.Webhook
+		if stored.SealedSecret != nil && sealer != nil {
+			secret, err := sealer.Open(stored.SealedSecret, id)
+			if err != nil {
+				log.Printf("Error decrypting secret of webhook %s: %v", id, err)
+			}
+			webhook.Secret = string(secret)
+		}
+		s.webhooks[id] = &webhook
+	}
+	for id, data := range store.Records(deliveriesCollection) {
+		if _, ok := s.webhooks[id]; !ok {
+			continue
+		}
+		var deliveries []*Delivery
+		if err := json.Unmarshal(data, &deliveries); err != nil {
+			return nil, fmt.Errorf("failed to decode deliveries of webhook %s: %w", id, err)
+		}
+		s.deliveries[id] = deliveries
+	}
+	return s, nil
+}
+
+// SetRetryPolicy sets the number of delivery attempts and the delay before the first retry,
+// which doubles after every failed attempt.
+func (s *Service) SetRetryPolicy(maxAttempts int, baseDelay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxAttempts = max(maxAttempts, 1)
+	s.baseDelay = baseDelay
+}
+
+// Start delivers the events published on events until the broker closes the subscription.
+// The subscription is unbounded, so that a burst of events does not skip deliveries.
+func (s *Service) Start(events *analysis.Broker) {
+	sub := events.SubscribeUnbounded(func(e analysis.Event) bool {
+		return e.Type == analysis.EventStatus && (e.Status == analysis.StatusCompleted || e.Status == analysis.StatusFailed)
+	})
+	go func() {
+		for e := range sub.C {
+			s.Dispatch(e)
+		}
+	}()
+}
+
+// Create validates and stores a new webhook. A signing secret is generated if none is given.
+// The returned webhook is the only place the secret is shown.
+func (s *Service) Create(webhook Webhook) (*Webhook, error) {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if len(webhook.Events) == 0 {
+		webhook.Events = []string{EventCompleted, EventFailed}
+	}
+	for _, event := range webhook.Events {
+		if !slices.Contains(validEvents, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	if webhook.Threshold < 0 {
+		return nil, fmt.Errorf("%w: threshold must not be negative", ErrInvalidWebhook)
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := webhook
+	if s.store != nil {
+		record := storedWebhook{Webhook: *withoutSecret(&stored)}
+		if s.sealer != nil {
+			record.SealedSecret, err = s.sealer.Seal([]byte(stored.Secret), stored.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to seal webhook secret: %w", err)
+			}
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode webhook: %w", err)
+		}
+		if err := s.store.PutRecord(webhooksCollection, stored.ID, data); err != nil {
+			return nil, fmt.Errorf("failed to store webhook: %w", err)
+		}
+	}
+	s.webhooks[webhook.ID] = &stored
+	return &webhook, nil
+}
+
+// GetAll returns every webhook, oldest first, without their secrets.
+func (s *Service) GetAll() []*Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks := make([]*Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, withoutSecret(webhook))
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+	return webhooks
+}
+
+// GetByID returns a webhook without its secret.
+func (s *Service) GetByID(id string) (*Webhook, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, false
+	}
+	return withoutSecret(webhook), true
+}
+
+// Delete removes a webhook and its delivery records.
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	if s.store != nil {
+		if err := s.store.DeleteRecord(webhooksCollection, id); err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		if err := s.store.DeleteRecord(deliveriesCollection, id); err != nil {
+			log.Printf("Error deleting deliveries of webhook %s: %v", id, err)
+		}
+	}
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+// Deliveries returns the most recent deliveries of a webhook, newest first.
+func (s *Service) Deliveries(id string) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return nil, ErrNotFound
+	}
+	deliveries := make([]Delivery, 0, len(s.deliveries[id]))
+	for i := len(s.deliveries[id]) - 1; i >= 0; i-- {
+		d := *s.deliveries[id][i]
+		d.Attempts = slices.Clone(d.Attempts)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func withoutSecret(webhook *Webhook) *Webhook {
+	c := *webhook
+	c.Secret = ""
+	return &c
+}
+
+// Dispatch delivers an analysis event to every webhook subscribed to it. Deliveries run in
+// the background.
+func (s *Service) Dispatch(e analysis.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, webhook := range s.webhooks {
+		for _, event := range eventsFor(e, webhook.Threshold) {
+			if !slices.Contains(webhook.Events, event) {
+				continue
+			}
+			delivery := &Delivery{
+				ID:         uuid.New().String(),
+				WebhookID:  webhook.ID,
+				Event:      event,
+				AnalysisID: e.ID,
+				CreatedAt:  time.Now(),
+			}
+			s.recordLocked(delivery)
+			go s.deliver(*webhook, delivery, e)
+		}
+	}
+}
+
+// eventsFor returns the webhook events raised by an analysis event.
+func eventsFor(e analysis.Event, threshold int) []string {
+	switch e.Status {
+	case analysis.StatusCompleted:
+		events := []string{EventCompleted}
+		if e.Issues != nil && e.Issues.Errors > threshold {
+			events = append(events, EventThresholdExceeded)
+		}
+		return events
+	case analysis.StatusFailed:
+		return []string{EventFailed}
+	}
+	return nil
+}
+
+// recordLocked keeps a delivery, dropping the oldest ones beyond the cap. s.mu must be held.
+func (s *Service) recordLocked(delivery *Delivery) {
+	deliveries := append(s.deliveries[delivery.WebhookID], delivery)
+	if len(deliveries) > maxDeliveriesPerWebhook {
+		deliveries = deliveries[len(deliveries)-maxDeliveriesPerWebhook:]
+	}
+	s.deliveries[delivery.WebhookID] = deliveries
+	s.saveDeliveriesLocked(delivery.WebhookID)
+}
+
+// saveDeliveriesLocked persists the delivery records of a webhook, unless it was deleted.
+// s.mu must be held.
+func (s *Service) saveDeliveriesLocked(webhookID string) {
+	if s.store == nil {
+		return
+	}
+	if _, ok := s.webhooks[webhookID]; !ok {
+		return
+	}
+	data, err := json.Marshal(s.deliveries[webhookID])
+	if err == nil {
+		err = s.store.PutRecord(deliveriesCollection, webhookID, data)
+	}
+	if err != nil {
+		log.Printf("Error storing deliveries of webhook %s: %v", webhookID, err)
+	}
+}
+
+// deliver posts the event to the webhook, retrying with exponential backoff until it gets a
+// 2xx response or runs out of attempts.
+func (s *Service) deliver(webhook Webhook, delivery *Delivery, e analysis.Event) {
+	body, err := json.Marshal(Payload{
+		Event:      delivery.Event,
+		DeliveryID: delivery.ID,
+		Timestamp:  delivery.CreatedAt,
+		Analysis:   e,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding webhook payload: %v\n", err)
+		return
+	}
+
+	s.mu.Lock()
+	maxAttempts, delay := s.maxAttempts, s.baseDelay
+	s.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		result := s.post(webhook, delivery, body)
+
+		s.mu.Lock()
+		delivery.Attempts = append(delivery.Attempts, result)
+		delivery.StatusCode = result.StatusCode
+		delivery.Delivered = result.Error == "" && result.StatusCode >= 200 && result.StatusCode < 300
+		done := delivery.Delivered
+		s.saveDeliveriesLocked(delivery.WebhookID)
+		s.mu.Unlock()
+
+		if done {
+			return
+		}
+		if attempt >= maxAttempts {
+			fmt.Fprintf(os.Stderr, "Giving up delivering %s event to webhook %s after %d attempts\n", delivery.Event, webhook.URL, attempt)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post makes a single delivery attempt.
+func (s *Service) post(webhook Webhook, delivery *Delivery, body []byte) Attempt {
+	start := time.Now()
+	attempt := Attempt{Time: start}
+	if webhook.Secret == "" {
+		attempt.Error = errSecretLost.Error()
+		return attempt
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pa11y-go-wrapper/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := s.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	attempt.StatusCode = resp.StatusCode
+	return attempt
+}
+
+// Sign returns the value of the signature header for body: "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
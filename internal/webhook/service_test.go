@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pa11y-go-wrapper/internal/analysis"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a local webhook endpoint that records the requests it gets and answers with the
+// queued status codes, then 200.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func waitForDelivery(t *testing.T, service *Service, id string, attempts int) Delivery {
+	t.Helper()
+	var delivery Delivery
+	require.Eventually(t, func() bool {
+		deliveries, err := service.Deliveries(id)
+		require.NoError(t, err)
+		if len(deliveries) == 0 {
+			return false
+		}
+		delivery = deliveries[0]
+		return delivery.Delivered || len(delivery.Attempts) >= attempts
+	}, 5*time.Second, 10*time.Millisecond)
+	return delivery
+}
+
+func TestCreateValidatesWebhooks(t *testing.T) {
+	service := NewService()
+
+	_, err := service.Create(Webhook{URL: "ftp://example.com"})
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = service.Create(Webhook{URL: "http://example.com", Events: []string{"started"}})
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+	created, err := service.Create(Webhook{URL: "http://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventCompleted, EventFailed}, created.Events)
+	assert.Len(t, created.Secret, 64, "a secret is generated")
+
+	stored, ok := service.GetByID(created.ID)
+	require.True(t, ok)
+	assert.Empty(t, stored.Secret, "the secret is only returned on creation")
+	assert.Len(t, service.GetAll(), 1)
+
+	require.NoError(t, service.Delete(created.ID))
+	assert.ErrorIs(t, service.Delete(created.ID), ErrNotFound)
+}
+
+func TestDeliverySignsPayload(t *testing.T) {
+	recv := newReceiver(t)
+	service := NewService()
+	broker := analysis.NewBroker()
+	service.Start(broker)
+
+	hook, err := service.Create(Webhook{
+		URL:       recv.URL,
+		Events:    []string{EventCompleted, EventThresholdExceeded},
+		Secret:    "s3cret",
+		Threshold: 1,
+	})
+	require.NoError(t, err)
+
+	broker.Publish(analysis.Event{Type: analysis.EventStatus, ID: "a1", Status: analysis.StatusProcessing})
+	broker.Publish(analysis.Event{
+		Type:   analysis.EventStatus,
+		ID:     "a1",
+		URL:    "http://example.com",
+		Status: analysis.StatusCompleted,
+		Issues: &analysis.IssueCounts{Errors: 2, Total: 2},
+	})
+
+	require.Eventually(t, func() bool { return recv.count() == 2 }, 5*time.Second, 10*time.Millisecond)
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+
+	events := map[string]bool{}
+	for i, req := range recv.requests {
+		assert.Equal(t, Sign("s3cret", recv.bodies[i]), req.Header.Get(SignatureHeader))
+		assert.NotEmpty(t, req.Header.Get(DeliveryHeader))
+
+		var payload Payload
+		require.NoError(t, json.Unmarshal(recv.bodies[i], &payload))
+		assert.Equal(t, req.Header.Get(EventHeader), payload.Event)
+		assert.Equal(t, "a1", payload.Analysis.ID)
+		assert.Equal(t, 2, payload.Analysis.Issues.Errors)
+		events[payload.Event] = true
+	}
+	assert.Equal(t, map[string]bool{EventCompleted: true, EventThresholdExceeded: true}, events)
+
+	deliveries, err := service.Deliveries(hook.ID)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+}
+
+func TestDeliveryRetriesWithBackoff(t *testing.T) {
+	recv := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	service := NewService()
+	service.SetRetryPolicy(3, 20*time.Millisecond)
+
+	hook, err := service.Create(Webhook{URL: recv.URL, Events: []string{EventFailed}})
+	require.NoError(t, err)
+
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a1", Status: analysis.StatusCompleted})
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a2", Status: analysis.StatusFailed, ErrorMessage: "boom"})
+
+	delivery := waitForDelivery(t, service, hook.ID, 3)
+	assert.True(t, delivery.Delivered)
+	assert.Equal(t, "a2", delivery.AnalysisID, "only subscribed events are delivered")
+	assert.Equal(t, http.StatusOK, delivery.StatusCode)
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusInternalServerError, delivery.Attempts[0].StatusCode)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[1].StatusCode)
+
+	firstGap := delivery.Attempts[1].Time.Sub(delivery.Attempts[0].Time)
+	secondGap := delivery.Attempts[2].Time.Sub(delivery.Attempts[1].Time)
+	assert.GreaterOrEqual(t, firstGap, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, secondGap, 40*time.Millisecond, "the delay doubles")
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	recv := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	service := NewService()
+	service.SetRetryPolicy(2, time.Millisecond)
+
+	hook, err := service.Create(Webhook{URL: recv.URL})
+	require.NoError(t, err)
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a1", Status: analysis.StatusFailed})
+
+	delivery := waitForDelivery(t, service, hook.ID, 2)
+	assert.False(t, delivery.Delivered)
+	assert.Len(t, delivery.Attempts, 2)
+	assert.Equal(t, http.StatusBadGateway, delivery.StatusCode)
+	assert.Equal(t, 2, recv.count())
+}
+
+func TestWebhooksSurviveRestart(t *testing.T) {
+	recv := newReceiver(t)
+	path := filepath.Join(t.TempDir(), "analyses.db")
+
+	sealer, err := analysis.NewSealer("profiles-key")
+	require.NoError(t, err)
+	store, err := analysis.NewBoltStore(path)
+	require.NoError(t, err)
+	service, err := NewServiceWithStore(store, sealer)
+	require.NoError(t, err)
+	hook, err := service.Create(Webhook{URL: recv.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+	deleted, err := service.Create(Webhook{URL: recv.URL})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(deleted.ID))
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a1", Status: analysis.StatusFailed})
+	waitForDelivery(t, service, hook.ID, 1)
+	assert.NotContains(t, string(store.Records(webhooksCollection)[hook.ID]), "s3cret", "the secret is sealed")
+	require.NoError(t, store.Close())
+
+	store, err = analysis.NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	service, err = NewServiceWithStore(store, sealer)
+	require.NoError(t, err)
+
+	webhooks := service.GetAll()
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, hook.ID, webhooks[0].ID)
+	deliveries, err := service.Deliveries(hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.True(t, deliveries[0].Delivered)
+
+	// The secret survives too, so deliveries are still signed with it.
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a2", Status: analysis.StatusFailed})
+	require.Eventually(t, func() bool { return recv.count() == 2 }, 5*time.Second, 10*time.Millisecond)
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	assert.Equal(t, Sign("s3cret", recv.bodies[1]), recv.requests[1].Header.Get(SignatureHeader))
+}
+
+func TestWebhookSecretsStayInMemoryWithoutKey(t *testing.T) {
+	recv := newReceiver(t)
+	path := filepath.Join(t.TempDir(), "analyses.db")
+
+	store, err := analysis.NewBoltStore(path)
+	require.NoError(t, err)
+	service, err := NewServiceWithStore(store, nil)
+	require.NoError(t, err)
+	hook, err := service.Create(Webhook{URL: recv.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(store.Records(webhooksCollection)[hook.ID]), "s3cret")
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a1", Status: analysis.StatusFailed})
+	assert.True(t, waitForDelivery(t, service, hook.ID, 1).Delivered)
+	require.NoError(t, store.Close())
+
+	// After a restart the webhook is still listed, but it cannot sign deliveries any more.
+	store, err = analysis.NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	service, err = NewServiceWithStore(store, nil)
+	require.NoError(t, err)
+	service.SetRetryPolicy(1, 0)
+	service.Dispatch(analysis.Event{Type: analysis.EventStatus, ID: "a2", Status: analysis.StatusFailed})
+	require.Eventually(t, func() bool {
+		deliveries, err := service.Deliveries(hook.ID)
+		return err == nil && len(deliveries) == 2 && len(deliveries[0].Attempts) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	deliveries, _ := service.Deliveries(hook.ID)
+	assert.False(t, deliveries[0].Delivered)
+	assert.Equal(t, errSecretLost.Error(), deliveries[0].Attempts[0].Error)
+	assert.Equal(t, 1, recv.count(), "unsigned deliveries are not sent")
+}
+
+func TestStartDeliversBursts(t *testing.T) {
+	recv := newReceiver(t)
+	service := NewService()
+	broker := analysis.NewBroker()
+	service.Start(broker)
+	_, err := service.Create(Webhook{URL: recv.URL, Events: []string{EventFailed}})
+	require.NoError(t, err)
+
+	const burst = 600
+	for i := range burst {
+		broker.Publish(analysis.Event{Type: analysis.EventStatus, ID: fmt.Sprint(i), Status: analysis.StatusFailed})
+	}
+	require.Eventually(t, func() bool { return recv.count() == burst }, 10*time.Second, 10*time.Millisecond)
+}